@@ -13,6 +13,8 @@ import (
 
 	"db-operator/pkg/apis"
 	"db-operator/pkg/controller"
+	nsfilter "db-operator/pkg/namespace"
+	"db-operator/pkg/webhook"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	kubemetrics "github.com/operator-framework/operator-sdk/pkg/kube-metrics"
@@ -23,6 +25,7 @@ import (
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
 	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -36,6 +39,12 @@ var (
 	metricsPort         int32 = 8383
 	operatorMetricsPort int32 = 8686
 )
+
+// Webhooks are served on webhookPort with the certificate from webhookCertDir
+var (
+	webhookPort    int32 = 9443
+	webhookCertDir       = "/tmp/k8s-webhook-server/serving-certs"
+)
 var log = logf.Log.WithName("cmd")
 
 func printVersion() {
@@ -53,6 +62,9 @@ func main() {
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	pflag.Int32Var(&webhookPort, "webhook-port", webhookPort, "Port the webhook server listens on")
+	pflag.StringVar(&webhookCertDir, "webhook-cert-dir", webhookCertDir, "Directory with tls.crt and tls.key for the webhook server")
+
 	pflag.Parse()
 
 	// Use a zap logr.Logger implementation. If none of the zap
@@ -93,6 +105,7 @@ func main() {
 		Namespace:          namespace,
 		MapperProvider:     restmapper.NewDynamicRESTMapper,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		NewCache:           nsfilter.NewCache(nsfilter.Watched),
 	})
 	if err != nil {
 		log.Error(err, "")
@@ -107,12 +120,24 @@ func main() {
 		os.Exit(1)
 	}
 
+	// CRDs are needed to keep track of the storage version migration
+	if err := apiextensionsv1beta1.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
+	// Setup all Webhooks
+	if err := webhook.AddToManager(mgr, &webhook.Server{Port: webhookPort, CertDir: webhookCertDir}); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	if err = serveCRMetrics(cfg); err != nil {
 		log.Info("Could not generate and serve custom resource metrics", "error", err.Error())
 	}
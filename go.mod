@@ -30,8 +30,10 @@ require (
 	github.com/onsi/gomega v1.5.0 // indirect
 	github.com/operator-framework/operator-sdk v0.9.0
 	github.com/pelletier/go-toml v1.4.0 // indirect
+	github.com/prometheus/client_golang v1.0.0
 	github.com/prometheus/common v0.6.0 // indirect
 	github.com/prometheus/procfs v0.0.3 // indirect
+	github.com/robfig/cron v0.0.0-20170526150127-736158dc09e1
 	github.com/sethvargo/go-password v0.1.2
 	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.4.0
@@ -41,7 +43,7 @@ require (
 	golang.org/x/tools v0.0.0-20190730215328-ed3277de2799 // indirect
 	google.golang.org/grpc v1.22.1 // indirect
 	k8s.io/api v0.0.0-20190726022912-69e1bce1dad5
-	k8s.io/apiextensions-apiserver v0.0.0-20190726024412-102230e288fd // indirect
+	k8s.io/apiextensions-apiserver v0.0.0-20190726024412-102230e288fd
 	k8s.io/apimachinery v0.0.0-20190730182816-1f8faeb81191
 	k8s.io/client-go v11.0.0+incompatible
 	k8s.io/klog v0.3.3 // indirect
@@ -0,0 +1,196 @@
+package apis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"db-operator/pkg/apis/db/v1alpha1"
+	"db-operator/pkg/apis/db/v1beta1"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// crdDirs hold the CRDs for apiextensions.k8s.io/v1beta1 and v1, both are generated from the types
+var crdDirs = []string{"../../deploy/crds", "../../deploy/crds/v1"}
+
+// types are the Go types of the served versions by version and kind
+var types = map[string]map[string]interface{}{
+	"v1alpha1": {
+		"Database": v1alpha1.Database{},
+	},
+	"v1beta1": {
+		"Database":               v1beta1.Database{},
+		"DatabaseQuota":          v1beta1.DatabaseQuota{},
+		"DatabaseBackup":         v1beta1.DatabaseBackup{},
+		"DatabaseBackupSchedule": v1beta1.DatabaseBackupSchedule{},
+	},
+}
+
+// TestCRDSchemas checks that the schema of every served version in the CRD manifests has the
+// fields of the Go types, so that a changed type without regenerated CRDs is noticed
+func TestCRDSchemas(t *testing.T) {
+	for _, dir := range crdDirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*_crd.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 0 {
+			t.Fatalf("no CRDs found in %s", dir)
+		}
+		for _, file := range files {
+			crd := readCRD(t, file)
+			kind := lookup(crd, "spec", "names", "kind").(string)
+			schemas := crdSchemas(crd)
+			if len(schemas) == 0 {
+				t.Errorf("%s: no schema found", file)
+			}
+			for version, schema := range schemas {
+				obj, ok := types[version][kind]
+				if !ok {
+					t.Errorf("%s: unknown kind %s of version %s", file, kind, version)
+					continue
+				}
+				compareSchema(t, file+" "+version, "", reflect.TypeOf(obj), schema)
+			}
+		}
+	}
+}
+
+func readCRD(t *testing.T, file string) map[string]interface{} {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	crd := map[string]interface{}{}
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(&crd); err != nil {
+		t.Fatalf("%s: %s", file, err)
+	}
+	return crd
+}
+
+// crdSchemas returns the schema of each version, apiextensions.k8s.io/v1beta1 CRDs with a
+// single version keep it in spec.validation
+func crdSchemas(crd map[string]interface{}) map[string]map[string]interface{} {
+	schemas := map[string]map[string]interface{}{}
+	versions, _ := lookup(crd, "spec", "versions").([]interface{})
+	for _, v := range versions {
+		version := v.(map[string]interface{})
+		if schema, ok := lookup(version, "schema", "openAPIV3Schema").(map[string]interface{}); ok {
+			schemas[version["name"].(string)] = schema
+		}
+	}
+	if schema, ok := lookup(crd, "spec", "validation", "openAPIV3Schema").(map[string]interface{}); ok {
+		schemas[lookup(crd, "spec", "version").(string)] = schema
+	}
+	return schemas
+}
+
+var (
+	timeType     = reflect.TypeOf(metav1.Time{})
+	durationType = reflect.TypeOf(metav1.Duration{})
+	quantityType = reflect.TypeOf(resource.Quantity{})
+	metaType     = reflect.TypeOf(metav1.ObjectMeta{})
+)
+
+// compareSchema reports fields of the Go type which are missing in the CRD schema, fields of
+// the schema which the type doesn't have and types which differ
+func compareSchema(t *testing.T, source, path string, typ reflect.Type, schema map[string]interface{}) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	var want string
+	switch {
+	case typ == timeType || typ == durationType:
+		want = "string"
+	case typ == quantityType:
+		// int-or-string
+		return
+	case typ.Kind() == reflect.String:
+		want = "string"
+	case typ.Kind() == reflect.Bool:
+		want = "boolean"
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Uint64:
+		want = "integer"
+	case typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64:
+		want = "number"
+	case typ.Kind() == reflect.Slice:
+		want = "array"
+	case typ.Kind() == reflect.Map || typ.Kind() == reflect.Struct:
+		want = "object"
+	}
+	if got := schema["type"]; want != "" && got != want {
+		t.Errorf("%s: %s has type %v, the Go type %s", source, path, got, want)
+	}
+
+	switch {
+	case typ.Kind() == reflect.Slice:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			compareSchema(t, source, path+"[]", typ.Elem(), items)
+		} else {
+			t.Errorf("%s: %s has no items", source, path)
+		}
+	case typ.Kind() == reflect.Struct && typ != metaType && typ.PkgPath() != timeType.PkgPath():
+		fields := jsonFields(typ)
+		properties, _ := schema["properties"].(map[string]interface{})
+		if got, want := keys(properties), keys(fields); got != want {
+			t.Errorf("%s: %s has fields [%s], the Go type [%s]", source, path, got, want)
+		}
+		for name, field := range fields {
+			if property, ok := properties[name].(map[string]interface{}); ok {
+				compareSchema(t, source, path+"."+name, field, property)
+			}
+		}
+	}
+}
+
+// jsonFields returns the types of the fields of a struct by their JSON name, with the
+// fields of inlined structs
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		switch {
+		case tag == "-" || f.PkgPath != "":
+		case strings.Contains(tag, ",inline"):
+			if f.Type.Kind() == reflect.Struct && name == "" {
+				for n, t := range jsonFields(f.Type) {
+					fields[n] = t
+				}
+			}
+		case name != "":
+			fields[name] = f.Type
+		}
+	}
+	return fields
+}
+
+func keys(m interface{}) string {
+	var names []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+func lookup(obj map[string]interface{}, path ...string) interface{} {
+	var v interface{} = obj
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
@@ -0,0 +1,132 @@
+package v1alpha1
+
+import (
+	"db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/conversion"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Fields which only exist in v1beta1 are kept in these annotations on v1alpha1 objects,
+// so an object read and written back as v1alpha1 doesn't lose them.
+const (
+	preservedSpecAnnotation   = "db.clarizen.cloud/v1beta1-spec"
+	preservedStatusAnnotation = "db.clarizen.cloud/v1beta1-status"
+)
+
+// ConvertTo converts this Database to the Hub version (v1beta1)
+func (src *Database) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.Database)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", dstRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	preserved := v1beta1.DatabaseSpec{}
+	if err := popAnnotation(&dst.ObjectMeta.Annotations, preservedSpecAnnotation, &preserved); err != nil {
+		return err
+	}
+	dst.Spec = specToHub(src.Spec, preserved)
+
+	dst.Status = v1beta1.DatabaseStatus{}
+	if err := popAnnotation(&dst.ObjectMeta.Annotations, preservedStatusAnnotation, &dst.Status); err != nil {
+		return err
+	}
+	dst.Status.Phase = src.Status.Phase
+	dst.Status.Error = src.Status.Error
+	dst.Status.Server = src.Status.Server
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version
+func (dst *Database) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.Database)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", srcRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec = DatabaseSpec{
+		Type:  src.Spec.Type,
+		Users: src.Spec.UserNames(),
+		Drop:  src.Spec.DeletionPolicy != "" && src.Spec.DeletionPolicy != v1beta1.DeletionPolicyRetain,
+	}
+	if !reflect.DeepEqual(specToHub(dst.Spec, v1beta1.DatabaseSpec{}), src.Spec) {
+		if err := setAnnotation(&dst.ObjectMeta.Annotations, preservedSpecAnnotation, src.Spec); err != nil {
+			return err
+		}
+	}
+
+	dst.Status = DatabaseStatus{
+		Phase:  src.Status.Phase,
+		Error:  src.Status.Error,
+		Server: src.Status.Server,
+	}
+	if !reflect.DeepEqual(v1beta1.DatabaseStatus{Phase: src.Status.Phase, Error: src.Status.Error, Server: src.Status.Server}, src.Status) {
+		if err := setAnnotation(&dst.ObjectMeta.Annotations, preservedStatusAnnotation, src.Status); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// specToHub builds the v1beta1 spec from the v1alpha1 one, taking everything
+// v1alpha1 can't express from the preserved spec
+func specToHub(in DatabaseSpec, preserved v1beta1.DatabaseSpec) v1beta1.DatabaseSpec {
+	out := preserved
+
+	out.Type = in.Type
+
+	users := map[string]v1beta1.DatabaseUser{}
+	for _, u := range preserved.Users {
+		users[u.Name] = u
+	}
+	out.Users = nil
+	for _, name := range in.Users {
+		u, ok := users[name]
+		if !ok {
+			u = v1beta1.DatabaseUser{Name: name}
+		}
+		out.Users = append(out.Users, u)
+	}
+
+	switch {
+	case !in.Drop && preserved.DeletionPolicy != "":
+		out.DeletionPolicy = v1beta1.DeletionPolicyRetain
+	case in.Drop && (preserved.DeletionPolicy == "" || preserved.DeletionPolicy == v1beta1.DeletionPolicyRetain):
+		out.DeletionPolicy = v1beta1.DeletionPolicyDelete
+	}
+
+	return out
+}
+
+func popAnnotation(annotations *map[string]string, key string, into interface{}) error {
+	raw, ok := (*annotations)[key]
+	if !ok {
+		return nil
+	}
+	delete(*annotations, key)
+	if len(*annotations) == 0 {
+		*annotations = nil
+	}
+
+	return json.Unmarshal([]byte(raw), into)
+}
+
+func setAnnotation(annotations *map[string]string, key string, from interface{}) error {
+	raw, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	if *annotations == nil {
+		*annotations = map[string]string{}
+	}
+	(*annotations)[key] = string(raw)
+
+	return nil
+}
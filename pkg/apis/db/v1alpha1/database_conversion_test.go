@@ -0,0 +1,167 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"db-operator/pkg/apis/db/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func hubDatabase() *v1beta1.Database {
+	// Timestamps are kept in seconds by JSON, the annotations wouldn't round trip anything finer
+	now := metav1.NewTime(time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC))
+	limit := int32(10)
+	soft := resource.MustParse("8Gi")
+	return &v1beta1.Database{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "team",
+			Annotations: map[string]string{"team": "a"},
+		},
+		Spec: v1beta1.DatabaseSpec{
+			Type: "postgres",
+			Users: []v1beta1.DatabaseUser{
+				{Name: "reader", RoleSettings: v1beta1.RoleSettings{ConnectionLimit: &limit, Parameters: map[string]string{"work_mem": "64MB"}}},
+				{Name: "writer"},
+			},
+			ServerRef:      &v1beta1.ServerReference{Name: "reporting"},
+			DeletionPolicy: v1beta1.DeletionPolicySoftDelete,
+			Settings:       &v1beta1.DatabaseSettings{Encoding: "UTF8", Template: "template0"},
+			Parameters:     map[string]string{"statement_timeout": "30s"},
+			Schemas:        []v1beta1.DatabaseSchema{{Name: "app", Grants: []v1beta1.SchemaGrant{{Role: "reader", Access: v1beta1.SchemaRead}}}},
+			Extensions:     []v1beta1.DatabaseExtension{{Name: "pgcrypto", Version: "1.3"}},
+			Protected:      true,
+			Quota:          &v1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse("10Gi"), SoftMaxSize: &soft},
+			TTL:            &metav1.Duration{Duration: 72 * time.Hour},
+		},
+		Status: v1beta1.DatabaseStatus{
+			Phase:  "Created",
+			Server: "reporting",
+			Conditions: []v1beta1.DatabaseCondition{
+				{Type: v1beta1.DatabaseAdmitted, Status: corev1.ConditionTrue, LastTransitionTime: now, Reason: "Admitted"},
+			},
+			Usage:     &v1beta1.DatabaseUsage{Size: resource.MustParse("1Gi"), Connections: 3, LastMeasured: now},
+			ExpiresAt: &now,
+		},
+	}
+}
+
+func TestHubRoundTrip(t *testing.T) {
+	for _, policy := range []v1beta1.DeletionPolicy{"", v1beta1.DeletionPolicyRetain, v1beta1.DeletionPolicyDelete, v1beta1.DeletionPolicySoftDelete} {
+		hub := hubDatabase()
+		hub.Spec.DeletionPolicy = policy
+
+		spoke := &Database{}
+		if err := spoke.ConvertFrom(hub.DeepCopy()); err != nil {
+			t.Fatalf("%q: ConvertFrom: %s", policy, err)
+		}
+		if spoke.Spec.Type != "postgres" || len(spoke.Spec.Users) != 2 || spoke.Status.Server != "reporting" {
+			t.Errorf("%q: fields v1alpha1 has weren't converted: %+v", policy, spoke)
+		}
+		if spoke.Annotations["team"] != "a" {
+			t.Errorf("%q: annotations of the object were lost: %v", policy, spoke.Annotations)
+		}
+		for _, key := range []string{preservedSpecAnnotation, preservedStatusAnnotation} {
+			if _, ok := spoke.Annotations[key]; !ok {
+				t.Errorf("%q: annotation %s is missing", policy, key)
+			}
+		}
+
+		back := &v1beta1.Database{}
+		if err := spoke.ConvertTo(back); err != nil {
+			t.Fatalf("%q: ConvertTo: %s", policy, err)
+		}
+		if !equality.Semantic.DeepEqual(hub, back) {
+			t.Errorf("%q: round trip changed the Database\nwant %+v\ngot  %+v", policy, hub, back)
+		}
+	}
+}
+
+func TestSpokeRoundTrip(t *testing.T) {
+	for _, drop := range []bool{false, true} {
+		spoke := &Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"},
+			Spec:       DatabaseSpec{Type: "postgres", Users: []string{"reader", "writer"}, Drop: drop},
+			Status:     DatabaseStatus{Phase: "Created", Server: "default"},
+		}
+
+		hub := &v1beta1.Database{}
+		if err := spoke.DeepCopy().ConvertTo(hub); err != nil {
+			t.Fatalf("drop=%t: ConvertTo: %s", drop, err)
+		}
+		back := &Database{}
+		if err := back.ConvertFrom(hub); err != nil {
+			t.Fatalf("drop=%t: ConvertFrom: %s", drop, err)
+		}
+		if !equality.Semantic.DeepEqual(spoke, back) {
+			t.Errorf("drop=%t: round trip changed the Database\nwant %+v\ngot  %+v", drop, spoke, back)
+		}
+		if len(back.Annotations) != 0 {
+			t.Errorf("drop=%t: nothing needs to be preserved, got annotations %v", drop, back.Annotations)
+		}
+	}
+}
+
+func TestDropToDeletionPolicy(t *testing.T) {
+	for _, c := range []struct {
+		preserved v1beta1.DeletionPolicy
+		drop      bool
+		want      v1beta1.DeletionPolicy
+	}{
+		{"", false, ""},
+		{"", true, v1beta1.DeletionPolicyDelete},
+		{v1beta1.DeletionPolicyRetain, false, v1beta1.DeletionPolicyRetain},
+		{v1beta1.DeletionPolicyRetain, true, v1beta1.DeletionPolicyDelete},
+		{v1beta1.DeletionPolicyDelete, false, v1beta1.DeletionPolicyRetain},
+		{v1beta1.DeletionPolicyDelete, true, v1beta1.DeletionPolicyDelete},
+		// drop can't tell SoftDelete from Delete, the preserved policy wins unless drop is turned off
+		{v1beta1.DeletionPolicySoftDelete, false, v1beta1.DeletionPolicyRetain},
+		{v1beta1.DeletionPolicySoftDelete, true, v1beta1.DeletionPolicySoftDelete},
+	} {
+		got := specToHub(DatabaseSpec{Type: "postgres", Drop: c.drop}, v1beta1.DatabaseSpec{DeletionPolicy: c.preserved}).DeletionPolicy
+		if got != c.want {
+			t.Errorf("preserved %q with drop=%t: got %q, want %q", c.preserved, c.drop, got, c.want)
+		}
+	}
+
+	for policy, drop := range map[v1beta1.DeletionPolicy]bool{
+		"":                               false,
+		v1beta1.DeletionPolicyRetain:     false,
+		v1beta1.DeletionPolicyDelete:     true,
+		v1beta1.DeletionPolicySoftDelete: true,
+	} {
+		spoke := &Database{}
+		hub := &v1beta1.Database{Spec: v1beta1.DatabaseSpec{Type: "postgres", DeletionPolicy: policy}}
+		if err := spoke.ConvertFrom(hub); err != nil {
+			t.Fatal(err)
+		}
+		if spoke.Spec.Drop != drop {
+			t.Errorf("%q: got drop=%t, want %t", policy, spoke.Spec.Drop, drop)
+		}
+	}
+}
+
+// Users edited in v1alpha1 keep the settings preserved for them, new ones get none
+func TestUsersEditedInSpoke(t *testing.T) {
+	spoke := &Database{}
+	if err := spoke.ConvertFrom(hubDatabase()); err != nil {
+		t.Fatal(err)
+	}
+	spoke.Spec.Users = []string{"reader", "admin"}
+
+	hub := &v1beta1.Database{}
+	if err := spoke.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	if len(hub.Spec.Users) != 2 || hub.Spec.Users[0].ConnectionLimit == nil || *hub.Spec.Users[0].ConnectionLimit != 10 {
+		t.Errorf("settings of reader were lost: %+v", hub.Spec.Users)
+	}
+	if hub.Spec.Users[1].Name != "admin" || hub.Spec.Users[1].ConnectionLimit != nil {
+		t.Errorf("admin should have no settings: %+v", hub.Spec.Users[1])
+	}
+}
@@ -4,18 +4,30 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// DatabaseSpec defines the desired state of Database
+// +k8s:openapi-gen=true
 type DatabaseSpec struct {
-	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
-	Type  string   `json:"type"`
-	Users []string `json:"users"`
-	Drop  bool     `json:"drop"`
+	// Type is the database engine, only postgres is supported for now
+	// +kubebuilder:validation:Enum=postgres,mysql
+	Type string `json:"type"`
+	// Users are existing database roles which get access to the database
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_$-]*$
+	// +kubebuilder:validation:MaxLength=63
+	Users []string `json:"users,omitempty"`
+	// Drop the database on the server when the object is deleted
+	Drop bool `json:"drop,omitempty"`
 }
 
+// DatabaseStatus defines the observed state of Database
+// +k8s:openapi-gen=true
 type DatabaseStatus struct {
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
-	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
-	Phase  string `json:"phase"`
-	Error  string `json:"error,omitempty"`
+
+	// Phase is the provisioning state of the database
+	// +kubebuilder:validation:Enum=Created,Unsupported,Error
+	Phase string `json:"phase,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Server is the name of the server the database was created on
 	Server string `json:"server,omitempty"`
 }
 
@@ -24,6 +36,10 @@ type DatabaseStatus struct {
 // Database is the Schema for the databases API
 // +k8s:openapi-gen=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type",description="Database engine"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Current phase"
+// +kubebuilder:printcolumn:name="Server",type="string",JSONPath=".status.server",description="Database server"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type Database struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
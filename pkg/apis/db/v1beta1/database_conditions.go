@@ -0,0 +1,38 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetCondition returns the condition of type t, or nil if it isn't set
+func (in *DatabaseStatus) GetCondition(t DatabaseConditionType) *DatabaseCondition {
+	for i := range in.Conditions {
+		if in.Conditions[i].Type == t {
+			return &in.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue reports if the condition of type t is set to True
+func (in *DatabaseStatus) IsConditionTrue(t DatabaseConditionType) bool {
+	c := in.GetCondition(t)
+	return c != nil && c.Status == corev1.ConditionTrue
+}
+
+// SetCondition adds or updates the condition of type t.
+// LastTransitionTime only changes when the status does.
+func (in *DatabaseStatus) SetCondition(t DatabaseConditionType, status corev1.ConditionStatus, reason, message string) {
+	c := in.GetCondition(t)
+	if c == nil {
+		in.Conditions = append(in.Conditions, DatabaseCondition{Type: t})
+		c = &in.Conditions[len(in.Conditions)-1]
+	}
+	if c.Status != status {
+		c.LastTransitionTime = metav1.Now()
+	}
+	c.Status = status
+	c.Reason = reason
+	c.Message = message
+}
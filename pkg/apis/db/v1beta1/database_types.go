@@ -0,0 +1,564 @@
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PausedAnnotation set to "true" stops the operator from touching the database until it's removed
+	PausedAnnotation = "db.clarizen.cloud/paused"
+	// ReconcileAtAnnotation triggers a reconcile whenever its value changes, e.g. set it to the current time
+	ReconcileAtAnnotation = "db.clarizen.cloud/reconcile-at"
+	// CloneToNamespacesAnnotation lists the namespaces, comma separated, which may clone the Database.
+	// "*" allows all namespaces, Databases can always be cloned within their own namespace.
+	CloneToNamespacesAnnotation = "db.clarizen.cloud/clone-to-namespaces"
+	// ExtendTTLAnnotation is a duration, e.g. 48h, added to spec.ttl
+	ExtendTTLAnnotation = "db.clarizen.cloud/extend-ttl"
+	// UndeleteAnnotation set to "true" on a new Database restores the latest soft deleted database
+	// of the same name and namespace, instead of creating an empty one
+	UndeleteAnnotation = "db.clarizen.cloud/undelete"
+	// ConfirmDropAnnotation set to the name of a protected database allows dropping it
+	ConfirmDropAnnotation = "db.clarizen.cloud/confirm-drop"
+)
+
+// DeletionPolicy describes what happens to the database on the server when the Database object is deleted
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyRetain keeps the database with its user and roles on the server, nothing is dropped
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyDelete drops the database from the server
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicySoftDelete renames the database to trash_<name>_<timestamp> and revokes all access.
+	// It's dropped after the trash retention of the operator, until then it can be undeleted.
+	DeletionPolicySoftDelete DeletionPolicy = "SoftDelete"
+)
+
+// DatabaseSpec defines the desired state of Database
+// +k8s:openapi-gen=true
+type DatabaseSpec struct {
+	// Type is the database engine, only postgres is supported for now
+	// +kubebuilder:validation:Enum=postgres,mysql
+	Type string `json:"type"`
+	// Users are existing database roles which get access to the database
+	Users []DatabaseUser `json:"users,omitempty"`
+	// ServerRef selects the database server from the operator configuration.
+	// If it's empty the operator places the database on the best fitting server.
+	// Changing it moves the database to the new server.
+	ServerRef *ServerReference `json:"serverRef,omitempty"`
+	// ServerSelector limits automatic placement to servers with these labels
+	ServerSelector map[string]string `json:"serverSelector,omitempty"`
+	// DeletionPolicy is applied to the database when the object is deleted, defaults to Retain
+	// +kubebuilder:validation:Enum=Retain,Delete,SoftDelete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Settings are the options of the database on the server
+	Settings *DatabaseSettings `json:"settings,omitempty"`
+	// UserSettings apply to the user the operator creates for the database
+	UserSettings *RoleSettings `json:"userSettings,omitempty"`
+	// Parameters are runtime parameters set for the database by ALTER DATABASE SET, e.g.
+	// statement_timeout: 30s. Only parameters allowed by the operator configuration are set.
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Schemas are created in the database, owned by its _owners role
+	Schemas []DatabaseSchema `json:"schemas,omitempty"`
+	// PruneSchemas drops schemas which are removed from spec.schemas, with everything in them
+	PruneSchemas bool `json:"pruneSchemas,omitempty"`
+	// Extensions are created in the database by the operator. Only extensions allowed by the
+	// server configuration are created, removed ones are dropped.
+	Extensions []DatabaseExtension `json:"extensions,omitempty"`
+	// Protected databases with the Delete policy aren't dropped until the deletion is
+	// confirmed by the confirm-drop annotation carrying the name of the database
+	Protected bool `json:"protected,omitempty"`
+	// PreDropBackup dumps the database to a backup storage before the Delete policy drops it.
+	// The database isn't dropped unless the dump succeeded.
+	PreDropBackup *PreDropBackup `json:"preDropBackup,omitempty"`
+	// Quota limits the disk space the database may use
+	Quota *DatabaseSizeQuota `json:"quota,omitempty"`
+	// RestoreFrom restores a backup into the database once it's created. Setting it on an
+	// existing database, or pointing it to another backup, replaces the content of the database.
+	RestoreFrom *RestoreSource `json:"restoreFrom,omitempty"`
+	// CloneFrom creates the database as a copy of another Database or of a template database
+	// on the server. It's only used when the database is created.
+	CloneFrom *CloneSource `json:"cloneFrom,omitempty"`
+	// TTL is how long the database lives after it's created. Once it's expired the operator
+	// deletes the Database object, the deletion policy applies as usual.
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// CloneSource is what a database is cloned from, exactly one of the fields is set
+// +k8s:openapi-gen=true
+type CloneSource struct {
+	// Database is a Database which may be cloned into the namespace of the new one
+	Database *DatabaseReference `json:"database,omitempty"`
+	// Template is a database listed in the templates of the server
+	Template string `json:"template,omitempty"`
+	// MaskingProfile is a ConfigMap in the namespace of the Database with rules which anonymize the
+	// copied data. Keys are columns as [schema.]table.column, values are one of nullify, hash,
+	// fakeEmail or fixed:<value>. Access to the database is given only after masking succeeded.
+	MaskingProfile string `json:"maskingProfile,omitempty"`
+}
+
+// DatabaseReference points to a Database, possibly in another namespace
+// +k8s:openapi-gen=true
+type DatabaseReference struct {
+	// Namespace defaults to the namespace of the referring object
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// RestoreSource is the backup a database is restored from
+// +k8s:openapi-gen=true
+type RestoreSource struct {
+	// BackupName is a DatabaseBackup in the namespace of the Database
+	BackupName string `json:"backupName"`
+}
+
+// DatabaseSettings are the options of CREATE DATABASE. Encoding, locale and template are used when
+// the database is created and can't be changed afterwards, the others are altered when they change.
+// +k8s:openapi-gen=true
+type DatabaseSettings struct {
+	// Encoding is the character set, e.g. UTF8
+	Encoding string `json:"encoding,omitempty"`
+	// LCCollate is the collation order, e.g. en_US.UTF-8
+	LCCollate string `json:"lcCollate,omitempty"`
+	// LCCtype is the character classification, e.g. en_US.UTF-8
+	LCCtype string `json:"lcCtype,omitempty"`
+	// ICULocale selects the ICU locale provider with this locale, it requires postgres 15 or later
+	ICULocale string `json:"icuLocale,omitempty"`
+	// Template is copied by CREATE DATABASE, template0 is needed for an encoding or locale other
+	// than the one of template1. Other templates have to be listed in the server configuration.
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_$-]*$
+	Template string `json:"template,omitempty"`
+	// ConnectionLimit is how many concurrent connections the database allows, -1 for no limit
+	// +kubebuilder:validation:Minimum=-1
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+	// Tablespace is where the database is stored, moving it requires that nobody is connected
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_$-]*$
+	Tablespace string `json:"tablespace,omitempty"`
+	// Owner is the role owning the database, either the user of the database, which has its name,
+	// or its <name>_owners role. The operator's user owns it if it's empty.
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_$-]*$
+	Owner string `json:"owner,omitempty"`
+}
+
+// ImmutableChanges returns the names of the settings which differ from old but can't be
+// changed after the database is created
+func (in *DatabaseSettings) ImmutableChanges(old *DatabaseSettings) []string {
+	if in == nil {
+		in = &DatabaseSettings{}
+	}
+	if old == nil {
+		old = &DatabaseSettings{}
+	}
+
+	var changed []string
+	for _, s := range []struct {
+		name     string
+		new, old string
+	}{
+		{"encoding", in.Encoding, old.Encoding},
+		{"lcCollate", in.LCCollate, old.LCCollate},
+		{"lcCtype", in.LCCtype, old.LCCtype},
+		{"icuLocale", in.ICULocale, old.ICULocale},
+		{"template", in.Template, old.Template},
+	} {
+		if s.new != s.old {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
+
+// OwnerRefusal is why spec.settings.owner can't own the database, it's empty if it can. Only
+// the roles the operator creates for the database may own it.
+func (in *Database) OwnerRefusal() string {
+	if in.Spec.Settings == nil || in.Spec.Settings.Owner == "" {
+		return ""
+	}
+	owner := in.Spec.Settings.Owner
+	if owner == in.Name || owner == in.Name+"_owners" {
+		return ""
+	}
+	return fmt.Sprintf("owner %s is not allowed, it has to be %s or %s_owners", owner, in.Name, in.Name)
+}
+
+// RoleSettingsRefusal is why the settings of spec.users can't be applied, it's empty if they can.
+// Connection limit and expiry may only be set for the roles the operator creates for the database.
+func (in *Database) RoleSettingsRefusal() string {
+	var set []string
+	for _, u := range in.Spec.Users {
+		if u.Name == in.Name {
+			continue
+		}
+		if u.ConnectionLimit != nil {
+			set = append(set, fmt.Sprintf("connectionLimit of user %s", u.Name))
+		}
+		if u.ValidUntil != nil {
+			set = append(set, fmt.Sprintf("validUntil of user %s", u.Name))
+		}
+	}
+	if len(set) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s can't be set, the role isn't managed by the operator", strings.Join(set, ", "))
+}
+
+// PreDropBackup configures the dump taken before a database is dropped
+// +k8s:openapi-gen=true
+type PreDropBackup struct {
+	// Storage is a backup storage of the operator configuration, the default storage if it's empty
+	Storage string `json:"storage,omitempty"`
+}
+
+// DatabaseSizeQuota limits the disk space of a database, it's checked each time usage is measured
+// +k8s:openapi-gen=true
+type DatabaseSizeQuota struct {
+	// MaxSize is the hard limit, above it CONNECT and CREATE are revoked until usage drops below it again
+	MaxSize resource.Quantity `json:"maxSize"`
+	// SoftMaxSize is the soft limit, above it a QuotaWarning condition and Events are raised.
+	// Defaults to 90% of MaxSize.
+	SoftMaxSize *resource.Quantity `json:"softMaxSize,omitempty"`
+}
+
+// SoftLimit returns the soft limit in bytes
+func (in *DatabaseSizeQuota) SoftLimit() int64 {
+	if in.SoftMaxSize != nil {
+		return in.SoftMaxSize.Value()
+	}
+	return in.MaxSize.Value() / 10 * 9
+}
+
+// DatabaseUser is an existing database role which gets access to the database
+// +k8s:openapi-gen=true
+type DatabaseUser struct {
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_$-]*$
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// The users are shared roles the operator didn't create, only their parameters in the database
+	// are set and reset once they're removed. Their connection limit and expiry apply on the whole
+	// server and are refused, they can only be set for the user of the database in userSettings.
+	RoleSettings `json:",inline"`
+}
+
+// DatabaseSchema is a schema in the database and the access of roles to it
+// +k8s:openapi-gen=true
+type DatabaseSchema struct {
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_$]*$
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// Grants give roles other than the users of the database access to the schema
+	Grants []SchemaGrant `json:"grants,omitempty"`
+}
+
+// DatabaseExtension is an extension installed in the database
+// +k8s:openapi-gen=true
+type DatabaseExtension struct {
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_-]*$
+	Name string `json:"name"`
+	// Version is updated to when it changes, the default version of the server is installed if it's empty
+	Version string `json:"version,omitempty"`
+	// Schema the objects of the extension are created in, it must exist. The operator creates
+	// extensions as its own user, so schemas owned by roles of the database, like the ones in
+	// spec.schemas, are refused.
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_$]*$
+	Schema string `json:"schema,omitempty"`
+}
+
+// SchemaAccess is a level of privileges on a schema and the objects in it
+type SchemaAccess string
+
+const (
+	// SchemaRead allows reading tables and sequences
+	SchemaRead SchemaAccess = "Read"
+	// SchemaReadWrite allows changing the data of tables and sequences as well
+	SchemaReadWrite SchemaAccess = "ReadWrite"
+	// SchemaAll grants all privileges, including creating objects in the schema
+	SchemaAll SchemaAccess = "All"
+)
+
+// SchemaGrant gives a role access to a schema. The privileges cover the existing objects and the
+// ones the _owners role creates later.
+// +k8s:openapi-gen=true
+type SchemaGrant struct {
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_$-]*$
+	Role string `json:"role"`
+	// +kubebuilder:validation:Enum=Read,ReadWrite,All
+	Access SchemaAccess `json:"access"`
+}
+
+// RoleSettings are the attributes of a role and its runtime parameters in the database
+// +k8s:openapi-gen=true
+type RoleSettings struct {
+	// ConnectionLimit is how many concurrent connections the role may open, -1 for no limit
+	// +kubebuilder:validation:Minimum=-1
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+	// ValidUntil is when the password of the role expires
+	ValidUntil *metav1.Time `json:"validUntil,omitempty"`
+	// Parameters are runtime parameters set for the role in the database by
+	// ALTER ROLE IN DATABASE SET, the same ones as in spec.parameters are allowed
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// UserNames returns the names of the users which get access to the database
+func (in *DatabaseSpec) UserNames() []string {
+	names := make([]string, 0, len(in.Users))
+	for _, u := range in.Users {
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+// ServerReference points to a database server defined in the operator configuration
+// +k8s:openapi-gen=true
+type ServerReference struct {
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+	Name string `json:"name"`
+}
+
+// DatabaseStatus defines the observed state of Database
+// +k8s:openapi-gen=true
+type DatabaseStatus struct {
+	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
+
+	// Phase is the provisioning state of the database
+	// +kubebuilder:validation:Enum=Created,Unsupported,Error
+	Phase string `json:"phase,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Server is the name of the server the database is placed on, it doesn't change afterwards
+	Server string `json:"server,omitempty"`
+	// Template is the database copied when the database was created, template1 by default
+	Template string `json:"template,omitempty"`
+	// Conditions are the latest observations of the database state
+	Conditions []DatabaseCondition `json:"conditions,omitempty"`
+	// Usage is the latest resource usage measured on the server
+	Usage *DatabaseUsage `json:"usage,omitempty"`
+	// Migration tracks moving the database to the server in spec.serverRef
+	Migration *DatabaseMigrationStatus `json:"migration,omitempty"`
+	// Restore tracks restoring the backup in spec.restoreFrom
+	Restore *DatabaseRestoreStatus `json:"restore,omitempty"`
+	// Clone tracks copying the source in spec.cloneFrom
+	Clone *DatabaseCloneStatus `json:"clone,omitempty"`
+	// ExpiresAt is when the Database is deleted because of spec.ttl
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// UndeletedFrom is the soft deleted database which was restored by the undelete annotation
+	UndeletedFrom string `json:"undeletedFrom,omitempty"`
+	// Parameters are the runtime parameters the operator set on the database, they're reset
+	// once they're removed from spec.parameters
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// RolesWithParameters are the users the operator set parameters for in the database,
+	// they're reset once the users are removed
+	RolesWithParameters []string `json:"rolesWithParameters,omitempty"`
+	// Schemas are the schemas the operator manages in the database with their grants
+	Schemas []DatabaseSchema `json:"schemas,omitempty"`
+	// Extensions are the extensions the operator manages in the database, as installed
+	Extensions []DatabaseExtension `json:"extensions,omitempty"`
+}
+
+// RestorePhase is the state of restoring a backup into a database
+type RestorePhase string
+
+const (
+	// RestoreRunning is while pg_restore runs, access to the database is blocked meanwhile
+	RestoreRunning RestorePhase = "Running"
+	// RestoreCompleted is after the backup is restored and the objects are handed to the database roles
+	RestoreCompleted RestorePhase = "Completed"
+	// RestoreFailed stops the restore, removing spec.restoreFrom and setting it again retries it
+	RestoreFailed RestorePhase = "Failed"
+)
+
+// DatabaseRestoreStatus tracks restoring a backup into a database
+// +k8s:openapi-gen=true
+type DatabaseRestoreStatus struct {
+	// Backup is the name of the DatabaseBackup which is restored
+	Backup string `json:"backup"`
+	// Location is where the dump is read from
+	Location string `json:"location,omitempty"`
+	// +kubebuilder:validation:Enum=Running,Completed,Failed
+	Phase RestorePhase `json:"phase"`
+	// StartTime is when the restore started
+	StartTime metav1.Time `json:"startTime"`
+	// CompletionTime is when the restore completed or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// MigrationStep is a step of moving a database between servers
+type MigrationStep string
+
+const (
+	// MigrationPreparing creates the database, its user and the _owners role on the target server
+	MigrationPreparing MigrationStep = "Preparing"
+	// MigrationCopying copies the schema to the target server and replicates the data while the
+	// database is still in use
+	MigrationCopying MigrationStep = "Copying"
+	// MigrationSyncing blocks writes on the source until the target replayed the last changes
+	MigrationSyncing MigrationStep = "Syncing"
+	// MigrationDraining is after the switch to the target, until the source is dropped
+	MigrationDraining MigrationStep = "Draining"
+	// MigrationFailed stops the migration, setting spec.serverRef back to the source cancels it
+	MigrationFailed MigrationStep = "Failed"
+)
+
+// DatabaseMigrationStatus tracks moving a database to another server
+// +k8s:openapi-gen=true
+type DatabaseMigrationStatus struct {
+	// Source is the server the database is moved from
+	Source string `json:"source"`
+	// Target is the server the database is moved to
+	Target string `json:"target"`
+	// +kubebuilder:validation:Enum=Preparing,Copying,Syncing,Draining,Failed
+	Step MigrationStep `json:"step"`
+	// StartTime is when the migration started
+	StartTime metav1.Time `json:"startTime"`
+	// SyncLSN is the WAL position of the source when writes were blocked, the target has
+	// caught up once it confirmed it
+	SyncLSN string `json:"syncLSN,omitempty"`
+	// DropSourceAfter is when the database is dropped from the source server
+	DropSourceAfter *metav1.Time `json:"dropSourceAfter,omitempty"`
+}
+
+// CloneMethod is how a database is cloned
+type CloneMethod string
+
+const (
+	// CloneTemplate creates the database with the source as template, the source must be on the same server
+	CloneTemplate CloneMethod = "Template"
+	// CloneDump copies the source with pg_dump and pg_restore, if it's on another server or in use
+	CloneDump CloneMethod = "Dump"
+)
+
+// DatabaseCloneStatus tracks cloning a database, its phase is like the one of a restore
+// +k8s:openapi-gen=true
+type DatabaseCloneStatus struct {
+	// Source is the cloned Database as namespace/name, or the template
+	Source string `json:"source"`
+	// Server is the server of the source
+	Server string `json:"server"`
+	// Database is the name of the source on the server
+	Database string `json:"database"`
+	// +kubebuilder:validation:Enum=Template,Dump
+	Method CloneMethod `json:"method"`
+	// +kubebuilder:validation:Enum=Running,Completed,Failed
+	Phase RestorePhase `json:"phase"`
+	// StartTime is when the clone started
+	StartTime metav1.Time `json:"startTime"`
+	// CompletionTime is when the clone completed or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Masking reports what the masking profile changed
+	Masking *MaskingReport `json:"masking,omitempty"`
+}
+
+// MaskingReport lists the data masked in a cloned database
+// +k8s:openapi-gen=true
+type MaskingReport struct {
+	// Profile is the ConfigMap with the masking rules
+	Profile string `json:"profile"`
+	// Tables are the masked tables, ordered by name
+	Tables []MaskedTable `json:"tables,omitempty"`
+}
+
+// MaskedTable is a table with masked columns
+// +k8s:openapi-gen=true
+type MaskedTable struct {
+	// Table is the table as schema.table
+	Table string `json:"table"`
+	// Columns are the masked columns
+	Columns []string `json:"columns"`
+	// Rows is the number of updated rows
+	Rows int64 `json:"rows"`
+}
+
+// DatabaseUsage is the resource usage of a database measured on the server
+// +k8s:openapi-gen=true
+type DatabaseUsage struct {
+	// Size is the disk space used by the database
+	Size resource.Quantity `json:"size"`
+	// Connections is the number of open connections to the database
+	Connections int32 `json:"connections"`
+	// TransactionIDAge is the age of the oldest unfrozen transaction ID, it grows towards wraparound without vacuum
+	TransactionIDAge int64 `json:"transactionIDAge"`
+	// LastMeasured is when the usage was measured
+	LastMeasured metav1.Time `json:"lastMeasured"`
+}
+
+// DatabaseConditionType is a valid value for DatabaseCondition.Type
+type DatabaseConditionType string
+
+const (
+	// DatabasePaused is true while the paused annotation is set
+	DatabasePaused DatabaseConditionType = "Paused"
+	// DatabaseAdmitted is false while the database is refused, by a DatabaseQuota of its namespace
+	// or because the namespace isn't allowed to use the server
+	DatabaseAdmitted DatabaseConditionType = "Admitted"
+	// DatabaseMigrating is true while the database is moved to another server
+	DatabaseMigrating DatabaseConditionType = "Migrating"
+	// DatabaseRestored is false while a backup is restored into the database or after the restore failed
+	DatabaseRestored DatabaseConditionType = "Restored"
+	// DatabaseCloned is false while the source in spec.cloneFrom is copied or after copying failed
+	DatabaseCloned DatabaseConditionType = "Cloned"
+	// DatabaseDeletionBlocked is true while deleting a protected database waits for the confirmation
+	DatabaseDeletionBlocked DatabaseConditionType = "DeletionBlocked"
+	// DatabaseSettingsApplied is false if the settings on the server differ from spec.settings
+	// and can't be changed
+	DatabaseSettingsApplied DatabaseConditionType = "SettingsApplied"
+	// DatabaseParametersApplied is false if spec.parameters has parameters the operator may not set
+	DatabaseParametersApplied DatabaseConditionType = "ParametersApplied"
+	// DatabaseRoleSettingsApplied is false if the settings of a user have parameters the operator may not set,
+	// or attributes of a role the operator didn't create
+	DatabaseRoleSettingsApplied DatabaseConditionType = "RoleSettingsApplied"
+	// DatabaseExtensionsApplied is false if spec.extensions has extensions the operator may not
+	// create, in schemas it doesn't own, or which can't be dropped
+	DatabaseExtensionsApplied DatabaseConditionType = "ExtensionsApplied"
+	// DatabaseQuotaWarning is true while the database is above the soft size limit
+	DatabaseQuotaWarning DatabaseConditionType = "QuotaWarning"
+	// DatabaseQuotaExceeded is true while the database is above the hard size limit and access is revoked
+	DatabaseQuotaExceeded DatabaseConditionType = "QuotaExceeded"
+)
+
+// DatabaseCondition describes the state of a database at a certain point
+// +k8s:openapi-gen=true
+type DatabaseCondition struct {
+	Type   DatabaseConditionType  `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// LastTransitionTime is the last time the condition changed status
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a machine readable explanation of the last transition
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable explanation of the last transition
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Database is the Schema for the databases API
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type",description="Database engine"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Current phase"
+// +kubebuilder:printcolumn:name="Server",type="string",JSONPath=".status.server",description="Database server"
+// +kubebuilder:printcolumn:name="Size",type="string",JSONPath=".status.usage.size",description="Disk space used",priority=1
+// +kubebuilder:printcolumn:name="Expires",type="string",JSONPath=".status.expiresAt",description="When the database is deleted"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type Database struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseSpec   `json:"spec,omitempty"`
+	Status DatabaseStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseList contains a list of Database
+type DatabaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Database `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Database{}, &DatabaseList{})
+}
@@ -0,0 +1,74 @@
+package v1beta1
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImmutableChanges(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		new, old *DatabaseSettings
+		want     []string
+	}{
+		{"unset", nil, nil, nil},
+		{"unchanged", &DatabaseSettings{Encoding: "UTF8", Template: "template0"}, &DatabaseSettings{Encoding: "UTF8", Template: "template0"}, nil},
+		{"mutable settings", &DatabaseSettings{Owner: "app", Tablespace: "fast", ConnectionLimit: new(int32)}, nil, nil},
+		{"encoding", &DatabaseSettings{Encoding: "LATIN1"}, &DatabaseSettings{Encoding: "UTF8"}, []string{"encoding"}},
+		{"removed", nil, &DatabaseSettings{LCCollate: "C", LCCtype: "C"}, []string{"lcCollate", "lcCtype"}},
+		{"added", &DatabaseSettings{ICULocale: "de-DE", Template: "template0"}, nil, []string{"icuLocale", "template"}},
+		{"all", &DatabaseSettings{Encoding: "UTF8", LCCollate: "C", LCCtype: "C", ICULocale: "und", Template: "base"}, &DatabaseSettings{},
+			[]string{"encoding", "lcCollate", "lcCtype", "icuLocale", "template"}},
+	} {
+		if got := c.new.ImmutableChanges(c.old); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: ImmutableChanges = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestOwnerRefusal(t *testing.T) {
+	for _, c := range []struct {
+		settings *DatabaseSettings
+		want     string
+	}{
+		{nil, ""},
+		{&DatabaseSettings{}, ""},
+		{&DatabaseSettings{Owner: "app"}, ""},
+		{&DatabaseSettings{Owner: "app_owners"}, ""},
+		{&DatabaseSettings{Owner: "postgres"}, "owner postgres is not allowed, it has to be app or app_owners"},
+		{&DatabaseSettings{Owner: "other_owners"}, "owner other_owners is not allowed, it has to be app or app_owners"},
+		{&DatabaseSettings{Owner: "App"}, "owner App is not allowed, it has to be app or app_owners"},
+	} {
+		db := &Database{ObjectMeta: metav1.ObjectMeta{Name: "app"}, Spec: DatabaseSpec{Settings: c.settings}}
+		if got := db.OwnerRefusal(); got != c.want {
+			t.Errorf("OwnerRefusal of %+v = %q, want %q", c.settings, got, c.want)
+		}
+	}
+}
+
+func TestRoleSettingsRefusal(t *testing.T) {
+	limit := int32(5)
+	now := metav1.Now()
+	for _, c := range []struct {
+		name  string
+		users []DatabaseUser
+		want  string
+	}{
+		{"no users", nil, ""},
+		{"own user", []DatabaseUser{{Name: "app", RoleSettings: RoleSettings{ConnectionLimit: &limit, ValidUntil: &now}}}, ""},
+		{"parameters of other users", []DatabaseUser{{Name: "reader", RoleSettings: RoleSettings{Parameters: map[string]string{"work_mem": "64MB"}}}}, ""},
+		{"connection limit of another user", []DatabaseUser{{Name: "app"}, {Name: "reader", RoleSettings: RoleSettings{ConnectionLimit: &limit}}},
+			"connectionLimit of user reader can't be set, the role isn't managed by the operator"},
+		{"all of other users", []DatabaseUser{
+			{Name: "reader", RoleSettings: RoleSettings{ConnectionLimit: &limit, ValidUntil: &now}},
+			{Name: "writer", RoleSettings: RoleSettings{ValidUntil: &now}},
+		}, "connectionLimit of user reader, validUntil of user reader, validUntil of user writer can't be set, the role isn't managed by the operator"},
+	} {
+		db := &Database{ObjectMeta: metav1.ObjectMeta{Name: "app"}, Spec: DatabaseSpec{Users: c.users}}
+		if got := db.RoleSettingsRefusal(); got != c.want {
+			t.Errorf("%s: RoleSettingsRefusal = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
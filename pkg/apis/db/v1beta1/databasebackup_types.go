@@ -0,0 +1,82 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackupPhase is the state of a DatabaseBackup
+type BackupPhase string
+
+const (
+	// BackupRunning is while pg_dump runs
+	BackupRunning BackupPhase = "Running"
+	// BackupCompleted is after the dump was stored
+	BackupCompleted BackupPhase = "Completed"
+	// BackupFailed is after pg_dump or storing the dump failed, it isn't retried
+	BackupFailed BackupPhase = "Failed"
+)
+
+// DatabaseBackupSpec defines the desired state of DatabaseBackup
+// +k8s:openapi-gen=true
+type DatabaseBackupSpec struct {
+	// DatabaseName is the Database in the same namespace which is backed up
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+	DatabaseName string `json:"databaseName"`
+	// Storage selects the backup storage from the operator configuration, the default storage is used if empty
+	Storage string `json:"storage,omitempty"`
+}
+
+// DatabaseBackupStatus defines the observed state of DatabaseBackup
+// +k8s:openapi-gen=true
+type DatabaseBackupStatus struct {
+	// +kubebuilder:validation:Enum=Running,Completed,Failed
+	Phase BackupPhase `json:"phase,omitempty"`
+	Error string      `json:"error,omitempty"`
+	// Server is the server the database was dumped from
+	Server string `json:"server,omitempty"`
+	// Storage is the backup storage the dump is kept in
+	Storage string `json:"storage,omitempty"`
+	// Location is where the dump is stored, pvc://<claim>/<path> or s3://<bucket>/<key>
+	Location string `json:"location,omitempty"`
+	// Size is the size of the compressed dump
+	Size *resource.Quantity `json:"size,omitempty"`
+	// StartTime is when the backup started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when the backup completed or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Duration is how long the backup took
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseBackup is the Schema for the databasebackups API
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:path=databasebackups
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Database",type="string",JSONPath=".spec.databaseName",description="Database which is backed up"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Current phase"
+// +kubebuilder:printcolumn:name="Size",type="string",JSONPath=".status.size",description="Size of the dump"
+// +kubebuilder:printcolumn:name="Location",type="string",JSONPath=".status.location",description="Where the dump is stored",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type DatabaseBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseBackupSpec   `json:"spec,omitempty"`
+	Status DatabaseBackupStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseBackupList contains a list of DatabaseBackup
+type DatabaseBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseBackup{}, &DatabaseBackupList{})
+}
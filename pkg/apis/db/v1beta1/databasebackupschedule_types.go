@@ -0,0 +1,65 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackupScheduleLabel is set on the DatabaseBackups created by a DatabaseBackupSchedule
+const BackupScheduleLabel = "db.clarizen.cloud/backup-schedule"
+
+// DatabaseBackupScheduleSpec defines the desired state of DatabaseBackupSchedule
+// +k8s:openapi-gen=true
+type DatabaseBackupScheduleSpec struct {
+	// DatabaseName is the Database in the same namespace which is backed up
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+	DatabaseName string `json:"databaseName"`
+	// Schedule is a cron expression, e.g. "0 3 * * *"
+	Schedule string `json:"schedule"`
+	// Storage selects the backup storage from the operator configuration, the default storage is used if empty
+	Storage string `json:"storage,omitempty"`
+	// Keep is the number of DatabaseBackups kept, older ones are deleted along with their dumps. Defaults to 7.
+	Keep *int32 `json:"keep,omitempty"`
+	// Suspend stops creating new backups
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// DatabaseBackupScheduleStatus defines the observed state of DatabaseBackupSchedule
+// +k8s:openapi-gen=true
+type DatabaseBackupScheduleStatus struct {
+	// LastScheduleTime is when the last backup was created
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastBackup is the name of the last DatabaseBackup
+	LastBackup string `json:"lastBackup,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseBackupSchedule is the Schema for the databasebackupschedules API
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:path=databasebackupschedules
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Database",type="string",JSONPath=".spec.databaseName",description="Database which is backed up"
+// +kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".spec.schedule",description="Cron schedule"
+// +kubebuilder:printcolumn:name="Last Backup",type="date",JSONPath=".status.lastScheduleTime",description="When the last backup was created"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type DatabaseBackupSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseBackupScheduleSpec   `json:"spec,omitempty"`
+	Status DatabaseBackupScheduleStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseBackupScheduleList contains a list of DatabaseBackupSchedule
+type DatabaseBackupScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseBackupSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseBackupSchedule{}, &DatabaseBackupScheduleList{})
+}
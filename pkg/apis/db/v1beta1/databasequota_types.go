@@ -0,0 +1,64 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseQuotaSpec defines the limits for Databases in a namespace
+// +k8s:openapi-gen=true
+type DatabaseQuotaSpec struct {
+	// MaxDatabases is the number of Databases which may be provisioned in the namespace
+	MaxDatabases *int32 `json:"maxDatabases,omitempty"`
+	// MaxStorage limits the sum of spec.quota.maxSize of all Databases in the namespace.
+	// Databases without a size quota are refused while it's set.
+	MaxStorage *resource.Quantity `json:"maxStorage,omitempty"`
+	// AllowedServers are the servers Databases in the namespace may use, all servers if empty.
+	// Servers have no classes yet, servers of a class have to be listed by name.
+	AllowedServers []string `json:"allowedServers,omitempty"`
+}
+
+// DatabaseQuotaStatus defines the observed usage of a DatabaseQuota
+// +k8s:openapi-gen=true
+type DatabaseQuotaStatus struct {
+	// Databases is the number of provisioned Databases in the namespace
+	Databases int32 `json:"databases"`
+	// Storage is the sum of spec.quota.maxSize of the Databases in the namespace
+	Storage resource.Quantity `json:"storage"`
+	// MeasuredStorage is the sum of the disk space measured for the Databases in the namespace
+	MeasuredStorage resource.Quantity `json:"measuredStorage"`
+	// Servers are the servers used by the Databases in the namespace
+	Servers []string `json:"servers,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseQuota is the Schema for the databasequotas API
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:path=databasequotas
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Databases",type="integer",JSONPath=".status.databases",description="Databases in the namespace"
+// +kubebuilder:printcolumn:name="Max Databases",type="integer",JSONPath=".spec.maxDatabases",description="Database limit"
+// +kubebuilder:printcolumn:name="Storage",type="string",JSONPath=".status.storage",description="Storage quota in use"
+// +kubebuilder:printcolumn:name="Max Storage",type="string",JSONPath=".spec.maxStorage",description="Storage limit"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type DatabaseQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseQuotaSpec   `json:"spec,omitempty"`
+	Status DatabaseQuotaStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseQuotaList contains a list of DatabaseQuota
+type DatabaseQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseQuota{}, &DatabaseQuotaList{})
+}
@@ -0,0 +1,170 @@
+// Package backup keeps database dumps in the backup storages of the operator config.
+package backup
+
+import (
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/jobs"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("backup")
+
+const defaultBackupStorageName = "default"
+
+func init() {
+	viper.SetDefault("s3Image", "minio/mc:RELEASE.2019-08-07T23-14-43Z")
+}
+
+// Storage is where dumps are kept. Storages are read from the "backupStorages"
+// list of the operator config, either a PersistentVolumeClaim in the job namespace
+// or an S3 compatible bucket.
+type Storage struct {
+	Name string     `mapstructure:"name"`
+	PVC  string     `mapstructure:"pvc"`
+	S3   *S3Storage `mapstructure:"s3"`
+}
+
+// S3Storage is a bucket of an S3 compatible service, e.g. MinIO
+type S3Storage struct {
+	// Endpoint is the URL of the service, e.g. https://s3.amazonaws.com or http://minio:9000
+	Endpoint  string `mapstructure:"endpoint"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"accessKey"`
+	SecretKey string `mapstructure:"secretKey"`
+}
+
+var (
+	storages       = map[string]*Storage{}
+	defaultStorage string
+)
+
+// ReadStorages reads the backup storages from the operator config, once it's loaded
+func ReadStorages() {
+	viper.SetDefault("defaultBackupStorage", defaultBackupStorageName)
+	defaultStorage = viper.GetString("defaultBackupStorage")
+
+	var configured []*Storage
+	if err := viper.UnmarshalKey("backupStorages", &configured); err != nil {
+		log.Error(err, "Failed to read backup storages from config")
+		os.Exit(1)
+	}
+	for _, s := range configured {
+		if (s.PVC == "") == (s.S3 == nil) {
+			log.Error(fmt.Errorf("exactly one of pvc and s3 must be set"), "Invalid backup storage", "Storage", s.Name)
+			os.Exit(1)
+		}
+		storages[s.Name] = s
+	}
+}
+
+// StorageFor returns the storage with name, or the default storage if name is empty
+func StorageFor(name string) (*Storage, error) {
+	if name == "" {
+		name = defaultStorage
+	}
+	s, ok := storages[name]
+	if !ok {
+		return nil, fmt.Errorf("backup storage %s is not configured", name)
+	}
+	return s, nil
+}
+
+// Location describes where key is stored, for humans
+func (s *Storage) Location(key string) string {
+	if s.S3 != nil {
+		return fmt.Sprintf("s3://%s/%s", s.S3.Bucket, key)
+	}
+	return fmt.Sprintf("pvc://%s/%s", s.PVC, key)
+}
+
+// DumpJob dumps the database at the connection string dsn to key in the storage and reports
+// the size of the dump
+func (s *Storage) DumpJob(dsn, key string) jobs.Spec {
+	dump := `pg_dump --format=custom --dbname="$SOURCE" > "$FILE"
+wc -c < "$FILE" > /dev/termination-log`
+	upload := `mc cp "$FILE" "store/$BUCKET/$KEY"
+wc -c < "$FILE" > /dev/termination-log`
+
+	job := s.job(key)
+	job.Env["SOURCE"] = dsn
+	if s.S3 != nil {
+		job.Steps = []jobs.Step{{Script: dump}, {Image: viper.GetString("s3Image"), Script: upload}}
+	} else {
+		job.Steps = []jobs.Step{{Script: `mkdir -p "$(dirname "$FILE")"` + "\n" + dump}}
+	}
+	return job
+}
+
+// RestoreJob restores the dump at key into the database at the connection string dsn. Ownership
+// and grants are left out like when copying databases, the objects are handed to the _owners
+// role afterwards.
+func (s *Storage) RestoreJob(dsn, key string) jobs.Spec {
+	restore := `pg_restore --no-owner --no-acl --clean --if-exists --exit-on-error --dbname="$TARGET" "$FILE"`
+	download := `mc cp "store/$BUCKET/$KEY" "$FILE"`
+
+	job := s.job(key)
+	job.Env["TARGET"] = dsn
+	if s.S3 != nil {
+		job.Steps = []jobs.Step{{Image: viper.GetString("s3Image"), Script: download}, {Script: restore}}
+	} else {
+		job.Steps = []jobs.Step{{Script: restore}}
+	}
+	return job
+}
+
+// DeleteJob removes key from the storage
+func (s *Storage) DeleteJob(key string) jobs.Spec {
+	job := s.job(key)
+	if s.S3 != nil {
+		job.Steps = []jobs.Step{{Image: viper.GetString("s3Image"), Script: `mc rm "store/$BUCKET/$KEY" || true`}}
+	} else {
+		job.Steps = []jobs.Step{{Script: `rm -f "$FILE"`}}
+	}
+	return job
+}
+
+// job mounts the storage, FILE is where key is on the local disk of the Job
+func (s *Storage) job(key string) jobs.Spec {
+	if s.S3 != nil {
+		// mc reads the credentials for the "store" alias from MC_HOST_store
+		endpoint, _ := url.Parse(s.S3.Endpoint)
+		if endpoint == nil {
+			endpoint = &url.URL{}
+		}
+		endpoint.User = url.UserPassword(s.S3.AccessKey, s.S3.SecretKey)
+
+		return jobs.Spec{
+			Env: map[string]string{
+				"FILE":          "/work/dump",
+				"KEY":           key,
+				"BUCKET":        s.S3.Bucket,
+				"MC_HOST_store": endpoint.String(),
+			},
+			Volumes: []corev1.Volume{{Name: "work", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+			Mounts:  []corev1.VolumeMount{{Name: "work", MountPath: "/work"}},
+		}
+	}
+
+	return jobs.Spec{
+		Env: map[string]string{
+			"FILE": path.Join("/backups", key),
+			"KEY":  key,
+		},
+		Volumes: []corev1.Volume{{Name: "backups", VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: s.PVC},
+		}}},
+		Mounts: []corev1.VolumeMount{{Name: "backups", MountPath: "/backups"}},
+	}
+}
+
+// Key is the path of the dump of a DatabaseBackup in the storage
+func Key(b *dbv1beta1.DatabaseBackup) string {
+	return path.Join(b.Namespace, b.Spec.DatabaseName, b.Name+".dump")
+}
@@ -0,0 +1,10 @@
+package controller
+
+import (
+	"db-operator/pkg/controller/databasequota"
+)
+
+func init() {
+	// AddToManagerFuncs is a list of functions to create controllers and add them to a manager.
+	AddToManagerFuncs = append(AddToManagerFuncs, databasequota.Add)
+}
@@ -0,0 +1,265 @@
+package database
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/jobs"
+	"db-operator/pkg/util"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// planClone resolves spec.cloneFrom of a database which is about to be created on srv.
+// Sources on srv are used as template, others are copied by a Job after the database is created.
+// refusal is why the source can't be cloned, it's empty if the clone is planned.
+func (r *ReconcileDatabase) planClone(db *dbv1beta1.Database, srv *server) (refusal string, err error) {
+	from := db.Spec.CloneFrom
+	st := &dbv1beta1.DatabaseCloneStatus{
+		Server:    srv.Name,
+		Method:    dbv1beta1.CloneTemplate,
+		Phase:     dbv1beta1.RestoreRunning,
+		StartTime: metav1.Now(),
+	}
+
+	switch {
+	case from.Template != "" && from.Database != nil:
+		return "cloneFrom must set either database or template", nil
+
+	case from.Template != "":
+		if !util.Contains(srv.Templates, from.Template) {
+			return fmt.Sprintf("template %s is not available on server %s", from.Template, srv.Name), nil
+		}
+		st.Source = from.Template
+		st.Database = from.Template
+
+	case from.Database != nil:
+		ns := from.Database.Namespace
+		if ns == "" {
+			ns = db.Namespace
+		}
+		source := &dbv1beta1.Database{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: ns, Name: from.Database.Name}, source); err != nil {
+			if errors.IsNotFound(err) {
+				return fmt.Sprintf("database %s/%s not found", ns, from.Database.Name), nil
+			}
+			return "", err
+		}
+		if ns != db.Namespace && !cloneAllowed(source, db.Namespace) {
+			return fmt.Sprintf("database %s/%s may not be cloned into namespace %s, see the %s annotation",
+				ns, source.Name, db.Namespace, dbv1beta1.CloneToNamespacesAnnotation), nil
+		}
+		if source.Status.Phase != "Created" {
+			return fmt.Sprintf("database %s/%s is not created yet", ns, source.Name), nil
+		}
+		if source.Spec.Type != db.Spec.Type {
+			return fmt.Sprintf("database %s/%s is not a %s database", ns, source.Name, db.Spec.Type), nil
+		}
+		sourceSrv, err := serverFor(source)
+		if err != nil {
+			return "", err
+		}
+		st.Source = fmt.Sprintf("%s/%s", ns, source.Name)
+		st.Server = sourceSrv.Name
+		st.Database = source.Name
+		if sourceSrv != srv {
+			st.Method = dbv1beta1.CloneDump
+		}
+
+	default:
+		return "cloneFrom must set either database or template", nil
+	}
+
+	// The profile is fixed now, the data is masked even if spec.cloneFrom changes meanwhile
+	if from.MaskingProfile != "" {
+		st.Masking = &dbv1beta1.MaskingReport{Profile: from.MaskingProfile}
+	}
+	db.Status.Clone = st
+	return "", nil
+}
+
+// clone finishes cloning the database after it's created. Templates are copied by CREATE DATABASE
+// already, otherwise a Job copies the source. The database is created with access revoked, it's
+// given back and the Secret is created once the copied objects are masked and handed to the
+// _owners role of the new database.
+//
+// running is true while the Job runs and the database has to be reconciled again.
+func (r *ReconcileDatabase) clone(reqLogger logr.Logger, srv *server, db *dbv1beta1.Database, ev eventer) (running bool, err error) {
+	st := db.Status.Clone
+	if db.Status.Phase != "Created" || st == nil || st.Phase != dbv1beta1.RestoreRunning {
+		return false, nil
+	}
+
+	if c := db.Status.GetCondition(dbv1beta1.DatabaseCloned); c == nil || c.Reason != string(st.Method) {
+		reqLogger.Info("Cloning Database", "Source", st.Source, "Method", st.Method)
+		db.Status.SetCondition(dbv1beta1.DatabaseCloned, v1.ConditionFalse, string(st.Method),
+			fmt.Sprintf("Copying %s, access to the database is blocked", st.Source))
+		ev.normal(reasonCloning, "Copying %s from server %s", st.Source, st.Server)
+	}
+
+	if st.Method == dbv1beta1.CloneDump {
+		source, ok := servers[st.Server]
+		if !ok {
+			return false, fmt.Errorf("server %s is not configured", st.Server)
+		}
+
+		name := jobs.Name("clone", db)
+		state, err := r.jobs.Run(name, db, cloneJob(source, st.Database, srv, db.Name))
+		if err != nil {
+			return false, err
+		}
+		switch state {
+		case jobs.Running:
+			return true, nil
+		case jobs.Failed:
+			// The Job is kept for its logs until the database is deleted. Partly copied data
+			// may be unmasked, access stays blocked if a masking profile is set.
+			message := fmt.Sprintf("Copying %s failed, see Job %s/%s", st.Source, r.jobs.Namespace, name)
+			if st.Masking != nil {
+				message += ", access to the database stays blocked"
+			} else {
+				if err := r.cloneSecret(srv, db, ev); err != nil {
+					return false, err
+				}
+				if err := r.giveBackAccess(srv, db); err != nil {
+					return false, err
+				}
+			}
+			now := metav1.Now()
+			st.Phase = dbv1beta1.RestoreFailed
+			st.CompletionTime = &now
+			db.Status.SetCondition(dbv1beta1.DatabaseCloned, v1.ConditionFalse, string(dbv1beta1.RestoreFailed), message)
+			ev.warning(reasonCloneFailed, "Copying %s failed", st.Source)
+			return false, nil
+		}
+		if err := r.jobs.Cleanup(name); err != nil {
+			return false, err
+		}
+	}
+
+	if st.Masking != nil {
+		profile := st.Masking.Profile
+		tables, err := r.mask(srv, db, profile)
+		if err != nil {
+			// Access stays blocked, the data may be only partly masked
+			now := metav1.Now()
+			st.Phase = dbv1beta1.RestoreFailed
+			st.CompletionTime = &now
+			db.Status.SetCondition(dbv1beta1.DatabaseCloned, v1.ConditionFalse, "MaskingFailed",
+				fmt.Sprintf("Masking with profile %s failed, access to the database stays blocked: %s", profile, err))
+			ev.warning(reasonCloneFailed, "Masking with profile %s failed: %s", profile, err)
+			return false, nil
+		}
+		st.Masking.Tables = tables
+		ev.normal(reasonMasked, "Masked %d tables with profile %s", len(tables), profile)
+	}
+
+	// Templates on the server are owned by the admin, managed sources by their own roles
+	var owners []string
+	if db.Spec.CloneFrom != nil && db.Spec.CloneFrom.Database != nil && st.Method == dbv1beta1.CloneTemplate {
+		owners = []string{st.Database + "_owners", st.Database}
+	}
+	if err := srv.postgresOwnObjects(db.Name, owners...); err != nil {
+		return false, err
+	}
+	if err := r.cloneSecret(srv, db, ev); err != nil {
+		return false, err
+	}
+	if err := r.giveBackAccess(srv, db); err != nil {
+		return false, err
+	}
+
+	now := metav1.Now()
+	st.Phase = dbv1beta1.RestoreCompleted
+	st.CompletionTime = &now
+	db.Status.SetCondition(dbv1beta1.DatabaseCloned, v1.ConditionTrue, string(dbv1beta1.RestoreCompleted),
+		fmt.Sprintf("Cloned %s", st.Source))
+	ev.normal(reasonCloned, "Cloned %s using %s", st.Source, strings.ToLower(string(st.Method)))
+	return false, nil
+}
+
+// cloneSecret gives the user of the clone a new password and creates its Secret, unless an
+// earlier attempt did. The password set when the database was created is never handed out.
+func (r *ReconcileDatabase) cloneSecret(srv *server, db *dbv1beta1.Database, ev eventer) error {
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: db.Namespace, Name: secretName(db)}, &v1.Secret{})
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+	usr := &user{username: db.Name}
+	if usr.password, err = genPassword(); err != nil {
+		return err
+	}
+	if err := srv.postgresSetPassword(usr); err != nil {
+		return err
+	}
+	return r.createSecret(srv, db, usr, ev)
+}
+
+// mask applies the masking profile in the ConfigMap named profile to the cloned database
+func (r *ReconcileDatabase) mask(srv *server, db *dbv1beta1.Database, profile string) ([]dbv1beta1.MaskedTable, error) {
+	cm := &v1.ConfigMap{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: db.Namespace, Name: profile}, cm); err != nil {
+		return nil, err
+	}
+	rules, err := parseMaskingProfile(cm.Data)
+	if err != nil {
+		return nil, err
+	}
+	return srv.postgresMask(db.Name, rules)
+}
+
+// cloning reports if the database is being cloned, its Secret is created once that's done
+func cloning(db *dbv1beta1.Database) bool {
+	return db.Status.Clone != nil && db.Status.Clone.Phase == dbv1beta1.RestoreRunning
+}
+
+// cloneTemplate returns the database to create db from, if it's cloned from a template
+func cloneTemplate(db *dbv1beta1.Database) string {
+	if st := db.Status.Clone; st != nil && st.Phase == dbv1beta1.RestoreRunning && st.Method == dbv1beta1.CloneTemplate {
+		return st.Database
+	}
+	return ""
+}
+
+// templateRefusal is why spec.settings.template can't be used on srv, it's empty if it can.
+// Other databases may only be copied with spec.cloneFrom, which checks access to them.
+func templateRefusal(db *dbv1beta1.Database, srv *server) string {
+	if db.Spec.Settings == nil || db.Spec.Settings.Template == "" {
+		return ""
+	}
+	template := db.Spec.Settings.Template
+	switch {
+	case db.Spec.CloneFrom != nil:
+		return "settings.template can't be combined with cloneFrom"
+	case template == "template0" || template == "template1" || util.Contains(srv.Templates, template):
+		return ""
+	default:
+		return fmt.Sprintf("template %s is not available on server %s", template, srv.Name)
+	}
+}
+
+// cloneAllowed reports if source may be cloned into the namespace ns
+func cloneAllowed(source *dbv1beta1.Database, ns string) bool {
+	for _, allowed := range strings.Split(source.GetAnnotations()[dbv1beta1.CloneToNamespacesAnnotation], ",") {
+		if allowed = strings.TrimSpace(allowed); allowed == "*" || allowed == ns {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneJob copies the database sourceDB on source into targetDB on target
+func cloneJob(source *server, sourceDB string, target *server, targetDB string) jobs.Spec {
+	return jobs.Spec{
+		Steps: []jobs.Step{{Script: fmt.Sprintf(copyScript, "")}},
+		Env: map[string]string{
+			"SOURCE": source.dsn(sourceDB),
+			"TARGET": target.dsn(targetDB),
+		},
+	}
+}
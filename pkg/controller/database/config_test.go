@@ -0,0 +1,10 @@
+package database
+
+import "github.com/spf13/viper"
+
+// The operator config is read by init, which runs after the package variables are initialized.
+// The tests bring their own so that they don't depend on the working directory or /config.
+var _ = func() bool {
+	viper.AddConfigPath("testdata")
+	return true
+}()
@@ -2,24 +2,29 @@ package database
 
 import (
 	"context"
-	dbv1alpha1 "db-operator/pkg/apis/db/v1alpha1"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/jobs"
+	"db-operator/pkg/namespace"
+	"db-operator/pkg/quota"
+	"db-operator/pkg/util"
 	"fmt"
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"os"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/source"
-	"strings"
 	"time"
 )
 
@@ -30,12 +35,31 @@ const dbFinalizer = "finalizer.db.clarizen.cloud"
 // Add creates a new Database Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	r, err := newReconciler(mgr)
+	if err != nil {
+		return err
+	}
+	if err := add(mgr, r); err != nil {
+		return err
+	}
+	// Soft deleted databases are purged in the background
+	return mgr.Add(r.trash)
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileDatabase{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager) (*ReconcileDatabase, error) {
+	runner, err := jobs.NewRunner(mgr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReconcileDatabase{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetRecorder("database-controller"),
+		jobs:     runner,
+		trash:    newTrashBin(runner),
+	}, nil
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -46,35 +70,83 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Objects outside of the watched namespaces are filtered by the manager cache
 	pred := predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			// Ignore updates to CR status in which case metadata.Generation does not change
 			// Metadata.generation changes if Spec was changed
 			log.Info("Update event")
 
-			return inWatchedNamespace(e.MetaNew.GetNamespace()) && e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration()
+			return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() ||
+				annotationChanged(e.MetaOld, e.MetaNew, dbv1beta1.PausedAnnotation) ||
+				annotationChanged(e.MetaOld, e.MetaNew, dbv1beta1.ReconcileAtAnnotation) ||
+				annotationChanged(e.MetaOld, e.MetaNew, dbv1beta1.ExtendTTLAnnotation) ||
+				annotationChanged(e.MetaOld, e.MetaNew, dbv1beta1.ConfirmDropAnnotation)
 		},
 		CreateFunc: func(e event.CreateEvent) bool {
 			watchTime := time.Now().Add(-1 * time.Minute)
 			creationTime := e.Meta.GetCreationTimestamp()
 
-			return inWatchedNamespace(e.Meta.GetNamespace()) && creationTime.After(watchTime)
-		},
-		DeleteFunc: func(e event.DeleteEvent) bool {
-			return inWatchedNamespace(e.Meta.GetNamespace())
-		},
-		GenericFunc: func(e event.GenericEvent) bool {
-			return inWatchedNamespace(e.Meta.GetNamespace())
+			// Work which is only tracked by requeues has to be picked up again after a restart
+			if db, ok := e.Object.(*dbv1beta1.Database); ok && pollsByRequeue(db) {
+				return true
+			}
+			return creationTime.After(watchTime)
 		},
 	}
 
 	// Watch for changes to primary resource Database
-	err = c.Watch(&source.Kind{Type: &dbv1alpha1.Database{}}, &handler.EnqueueRequestForObject{}, pred)
+	err = c.Watch(&source.Kind{Type: &dbv1beta1.Database{}}, &handler.EnqueueRequestForObject{}, pred)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Databases in namespaces which start being watched are reconciled once
+	watched := make(chan event.GenericEvent)
+	err = c.Watch(&source.Channel{Source: watched}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+	namespace.Watched.OnWatch(func(ns string) {
+		list := &dbv1beta1.DatabaseList{}
+		if err := mgr.GetClient().List(context.TODO(), &client.ListOptions{Namespace: ns}, list); err != nil {
+			log.Error(err, "Unable to list Databases in newly watched namespace", "Namespace", ns)
+			return
+		}
+		for i := range list.Items {
+			watched <- event.GenericEvent{Meta: &list.Items[i], Object: &list.Items[i]}
+		}
+	})
+
+	// Refused Databases are reconciled again when a DatabaseQuota of their namespace changes
+	err = c.Watch(&source.Kind{Type: &dbv1beta1.DatabaseQuota{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			return refusedDatabases(mgr.GetClient(), o.Meta.GetNamespace())
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Databases restoring a backup wait for it to complete
+	err = c.Watch(&source.Kind{Type: &dbv1beta1.DatabaseBackup{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			return restoringDatabases(mgr.GetClient(), o.Meta.GetNamespace(), o.Meta.GetName())
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := metrics.Registry.Register(&managedCollector{client: mgr.GetClient()}); err != nil {
+		return err
+	}
+
+	if err := mgr.Add(newUsageCollector(mgr)); err != nil {
+		return err
+	}
+
+	return mgr.Add(migrateStorageVersion(mgr))
 }
 
 // blank assignment to verify that ReconcileDatabase implements reconcile.Reconciler
@@ -84,8 +156,11 @@ var _ reconcile.Reconciler = &ReconcileDatabase{}
 type ReconcileDatabase struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
-	scheme *runtime.Scheme
+	client   client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	jobs     *jobs.Runner
+	trash    *trashBin
 }
 
 // Reconcile reads that state of the cluster for a Database object and makes changes based on the state read
@@ -98,7 +173,7 @@ func (r *ReconcileDatabase) Reconcile(request reconcile.Request) (reconcile.Resu
 	reqLogger.Info("Reconciling Database")
 
 	// Fetch the Database instance
-	instance := &dbv1alpha1.Database{}
+	instance := &dbv1beta1.Database{}
 	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -109,20 +184,49 @@ func (r *ReconcileDatabase) Reconcile(request reconcile.Request) (reconcile.Resu
 		return reconcile.Result{}, err
 	}
 
+	// Paused databases are left alone, deletion included, until the annotation is removed
+	if instance.GetAnnotations()[dbv1beta1.PausedAnnotation] == "true" {
+		return reconcile.Result{}, r.pause(reqLogger, instance)
+	}
+	if instance.Status.IsConditionTrue(dbv1beta1.DatabasePaused) {
+		reqLogger.Info("Resuming Database")
+		instance.Status.SetCondition(dbv1beta1.DatabasePaused, v1.ConditionFalse, "Resumed", "")
+	}
+
+	if instance.Status.Server != "" {
+		instance.Status.Server = pinnedServer(instance.Status.Server)
+	}
+
+	ev := eventer{recorder: r.recorder, object: instance}
+	if instance.Status.Error != "" {
+		ev.normal(reasonRetrying, "Retrying after previous failure: %s", instance.Status.Error)
+	}
+
 	isDbMarkedToBeDeleted := instance.GetDeletionTimestamp() != nil
 	if isDbMarkedToBeDeleted {
-		if contains(instance.GetFinalizers(), dbFinalizer) {
+		if util.Contains(instance.GetFinalizers(), dbFinalizer) {
+			if blocked, err := r.guardDrop(reqLogger, instance, ev); blocked || err != nil {
+				return reconcile.Result{}, err
+			}
+			done, err := r.backupBeforeDrop(reqLogger, instance, ev)
+			if err != nil {
+				return r.failed(instance, ev, err)
+			}
+			if !done {
+				return reconcile.Result{RequeueAfter: jobs.PollInterval}, nil
+			}
+
 			// Run finalization logic for dbFinalizer. If the
 			// finalization logic fails, don't remove the finalizer so
 			// that we can retry during the next reconciliation.
-			if err := r.finalizeDatabase(reqLogger, instance); err != nil {
-				return reconcile.Result{}, err
+			if err := r.finalizeDatabase(reqLogger, instance, ev); err != nil {
+				return r.failed(instance, ev, err)
 			}
 
 			// Remove dbFinalizer. Once all finalizers have been
 			// removed, the object will be deleted.
-			instance.SetFinalizers(remove(instance.GetFinalizers(), dbFinalizer))
-			err := r.client.Update(context.TODO(), instance)
+			instance.SetFinalizers(util.Remove(instance.GetFinalizers(), dbFinalizer))
+			err = r.client.Update(context.TODO(), instance)
 			if err != nil {
 				return reconcile.Result{}, err
 			}
@@ -131,58 +235,246 @@ func (r *ReconcileDatabase) Reconcile(request reconcile.Request) (reconcile.Resu
 	}
 
 	// Add finalizer for this CR
-	if !contains(instance.GetFinalizers(), dbFinalizer) {
+	if !util.Contains(instance.GetFinalizers(), dbFinalizer) {
 		if err := r.addFinalizer(reqLogger, instance); err != nil {
 			return reconcile.Result{}, err
 		}
 	}
 
+	if expired, err := r.expire(reqLogger, instance, ev); expired || err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if result, done, err := r.migrate(reqLogger, instance, ev); done {
+		return result, err
+	}
+
+	// Server access and DatabaseQuotas are checked before the database is provisioned
+	ns := &v1.Namespace{}
+	if instance.Status.Phase == "" {
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: instance.Namespace}, ns); err != nil {
+			return r.failed(instance, ev, err)
+		}
+
+		// Nothing is on the server yet, the database simply follows serverRef
+		if instance.Spec.ServerRef != nil && instance.Status.Server != instance.Spec.ServerRef.Name {
+			instance.Status.Server = ""
+		}
+
+		// Undeleted databases stay on the server they were soft deleted on
+		if instance.GetAnnotations()[dbv1beta1.UndeleteAnnotation] == "true" && instance.Status.UndeletedFrom == "" {
+			refusal, err := r.planUndelete(instance)
+			if err != nil {
+				return r.failed(instance, ev, err)
+			}
+			if refusal != "" {
+				return reconcile.Result{}, r.refuse(reqLogger, instance, ev, "UndeleteNotPossible", fmt.Errorf("%s", refusal))
+			}
+		}
+
+		// Databases without serverRef are placed once and stay pinned to the server
+		if instance.Status.Server == "" && instance.Spec.ServerRef == nil {
+			srv, err := schedule(r.client, instance, ns)
+			if err != nil {
+				if _, ok := err.(*unschedulableError); ok {
+					// Capacity may free up without any event we watch
+					return reconcile.Result{RequeueAfter: time.Minute}, r.refuse(reqLogger, instance, ev, "Unschedulable", err)
+				}
+				return r.failed(instance, ev, err)
+			}
+			instance.Status.Server = srv.Name
+			ev.normal(reasonScheduled, "Database placed on server %s", srv.Name)
+		}
+	}
+
+	srv, err := serverFor(instance)
+	if err != nil {
+		return r.failed(instance, ev, err)
+	}
+
+	if instance.Status.Phase == "" {
+		if !srv.allows(ns) {
+			return reconcile.Result{}, r.refuse(reqLogger, instance, ev, "ServerNotAllowed",
+				fmt.Errorf("namespace %s is not allowed to use server %s", instance.Namespace, srv.Name))
+		}
+		if err := quota.Admit(r.client, instance, nil, srv.Name); err != nil {
+			if quota.IsExceeded(err) {
+				return reconcile.Result{}, r.refuse(reqLogger, instance, ev, "QuotaExceeded", err)
+			}
+			return r.failed(instance, ev, err)
+		}
+		if refusal := templateRefusal(instance, srv); refusal != "" {
+			return reconcile.Result{}, r.refuse(reqLogger, instance, ev, "TemplateNotAllowed", fmt.Errorf("%s", refusal))
+		}
+		if instance.Spec.CloneFrom != nil && instance.Status.Clone == nil {
+			refusal, err := r.planClone(instance, srv)
+			if err != nil {
+				return r.failed(instance, ev, err)
+			}
+			if refusal != "" {
+				// The source may become available without any event we watch
+				return reconcile.Result{RequeueAfter: time.Minute}, r.refuse(reqLogger, instance, ev, "CloneNotPossible", fmt.Errorf("%s", refusal))
+			}
+		}
+		instance.Status.SetCondition(dbv1beta1.DatabaseAdmitted, v1.ConditionTrue, "Admitted", "")
+	}
+
 	usr := &user{}
+	creating := instance.Status.Phase == ""
 	// Check if this Database already exists and status is "Created"
-	err = updateEvent(instance, usr)
+	err = updateEvent(srv, instance, usr, ev)
 	if err != nil {
-		return reconcile.Result{}, err
+		return r.failed(instance, ev, err, usr.password)
 	}
-
-	// If secret was set, we have to create k8s secret
-	if usr.password != "" {
-		secret, err := updateSecret(instance, usr)
-		if err != nil {
-			return reconcile.Result{}, err
+	if creating && instance.Status.UndeletedFrom != "" {
+		if err := r.trash.forget(instance.Status.UndeletedFrom); err != nil {
+			reqLogger.Error(err, "Unable to remove the soft deleted database record", "Trash", instance.Status.UndeletedFrom)
 		}
-		err = r.client.Create(context.TODO(), secret)
-		if err != nil {
-			return reconcile.Result{}, err
+	}
+
+	// If secret was set, we have to create k8s secret. Clones get theirs once they're masked.
+	if usr.password != "" && !cloning(instance) {
+		if err := r.createSecret(srv, instance, usr, ev); err != nil {
+			return r.failed(instance, ev, err, usr.password)
 		}
 	}
 
+	// Clones and restores run once the database exists
+	copying, err := r.clone(reqLogger, srv, instance, ev)
+	if err == nil && !copying {
+		copying, err = r.restore(reqLogger, srv, instance, ev)
+	}
+	if err != nil {
+		return r.failed(instance, ev, err)
+	}
+
+	instance.Status.Error = ""
+
 	err = r.client.Status().Update(context.TODO(), instance)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	if copying {
+		return reconcile.Result{RequeueAfter: jobs.PollInterval}, nil
+	}
 
-	// Database created/updated successfully - don't requeue
-	return reconcile.Result{}, nil
+	// Database created/updated successfully - only requeue to finish a migration or to expire it
+	return requeueExpiry(instance, requeueMigration(instance)), nil
 }
 
-func (r *ReconcileDatabase) finalizeDatabase(reqLogger logr.Logger, m *dbv1alpha1.Database) error {
-	err := deleteEvent(m)
+// createSecret creates the Secret with the credentials of usr
+func (r *ReconcileDatabase) createSecret(srv *server, db *dbv1beta1.Database, usr *user, ev eventer) error {
+	secret, err := updateSecret(srv, db, usr)
+	if err != nil {
+		return err
+	}
+	if err := r.client.Create(context.TODO(), secret); err != nil {
+		return err
+	}
+	ev.normal(reasonSecretCreated, "Secret %s created", secret.Name)
+	return nil
+}
+
+// failed records err on the Database status and as an Event and hands it back
+// to the controller, which requeues the request with backoff
+func (r *ReconcileDatabase) failed(m *dbv1beta1.Database, ev eventer, err error, secrets ...string) (reconcile.Result, error) {
+	ev.failed(err, secrets...)
+	databasesFailed.WithLabelValues(m.Spec.Type, serverName(m)).Inc()
+
+	m.Status.Error = sanitize(err.Error(), secrets...)
+	if updateErr := r.client.Status().Update(context.TODO(), m); updateErr != nil {
+		log.Error(updateErr, "Unable to record error in status", "Db.Namespace", m.Namespace, "Db.Name", m.Name)
+	}
+
+	return reconcile.Result{}, fmt.Errorf("%s", m.Status.Error)
+}
+
+func (r *ReconcileDatabase) finalizeDatabase(reqLogger logr.Logger, m *dbv1beta1.Database, ev eventer) error {
+	if m.Status.Migration != nil {
+		if err := r.cleanupMigration(m, ev); err != nil {
+			return err
+		}
+		m.Status.Migration = nil
+	}
+	for _, name := range []string{jobs.Name("clone", m), jobs.Name("restore", m), jobs.Name("pre-drop", m)} {
+		if err := r.jobs.Cleanup(name); err != nil {
+			return err
+		}
+	}
+
+	srv, err := serverFor(m)
+	if err != nil {
+		return err
+	}
+
+	if m.Spec.DeletionPolicy == dbv1beta1.DeletionPolicySoftDelete {
+		err = r.trash.put(srv, m, ev)
+	} else {
+		err = deleteEvent(srv, m, ev)
+	}
 	if err != nil {
 		return err
 	}
 
 	err = r.client.Delete(context.TODO(), &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-db-secret", m.Name),
+			Name:      secretName(m),
 			Namespace: m.Namespace,
 		},
 	})
+	// The credentials must not outlive the finalizer
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
 
 	reqLogger.Info("Successfully finalized database")
 	return nil
 }
 
-func (r *ReconcileDatabase) addFinalizer(reqLogger logr.Logger, m *dbv1alpha1.Database) error {
+// guardDrop keeps a protected database from being dropped until the deletion is confirmed,
+// blocked is true while the finalizer has to wait
+func (r *ReconcileDatabase) guardDrop(reqLogger logr.Logger, m *dbv1beta1.Database, ev eventer) (blocked bool, err error) {
+	if !m.Spec.Protected || m.Spec.DeletionPolicy != dbv1beta1.DeletionPolicyDelete ||
+		m.GetAnnotations()[dbv1beta1.ConfirmDropAnnotation] == m.Name {
+		return false, nil
+	}
+	if m.Status.IsConditionTrue(dbv1beta1.DatabaseDeletionBlocked) {
+		return true, nil
+	}
+
+	msg := fmt.Sprintf("Database is protected, annotate it with %s=%s to drop it or change the deletion policy",
+		dbv1beta1.ConfirmDropAnnotation, m.Name)
+	reqLogger.Info("Deletion of protected Database is blocked")
+	ev.warning(reasonDeletionBlocked, "%s", msg)
+	m.Status.SetCondition(dbv1beta1.DatabaseDeletionBlocked, v1.ConditionTrue, "Protected", msg)
+	return true, r.client.Status().Update(context.TODO(), m)
+}
+
+// refuse records why the database isn't provisioned. There's nothing to retry until
+// the cause changes, e.g. a DatabaseQuota is updated, so no error is returned.
+func (r *ReconcileDatabase) refuse(reqLogger logr.Logger, m *dbv1beta1.Database, ev eventer, reason string, err error) error {
+	if c := m.Status.GetCondition(dbv1beta1.DatabaseAdmitted); c != nil && c.Status == v1.ConditionFalse && c.Message == err.Error() {
+		return nil
+	}
+
+	reqLogger.Info("Database is refused", "Reason", err.Error())
+	ev.warning(reasonRefused, "%s", err.Error())
+	m.Status.SetCondition(dbv1beta1.DatabaseAdmitted, v1.ConditionFalse, reason, err.Error())
+	return r.client.Status().Update(context.TODO(), m)
+}
+
+func (r *ReconcileDatabase) pause(reqLogger logr.Logger, m *dbv1beta1.Database) error {
+	if m.Status.IsConditionTrue(dbv1beta1.DatabasePaused) {
+		return nil
+	}
+
+	reqLogger.Info("Database is paused, skipping reconcile")
+	m.Status.SetCondition(dbv1beta1.DatabasePaused, v1.ConditionTrue, "PausedByAnnotation",
+		fmt.Sprintf("Remove the %s annotation to resume", dbv1beta1.PausedAnnotation))
+	return r.client.Status().Update(context.TODO(), m)
+}
+
+func (r *ReconcileDatabase) addFinalizer(reqLogger logr.Logger, m *dbv1beta1.Database) error {
 	reqLogger.Info("Adding Finalizer for the Database")
 	m.SetFinalizers(append(m.GetFinalizers(), dbFinalizer))
 
@@ -195,26 +487,29 @@ func (r *ReconcileDatabase) addFinalizer(reqLogger logr.Logger, m *dbv1alpha1.Da
 	return nil
 }
 
-func contains(list []string, s string) bool {
-	for _, v := range list {
-		if v == s {
-			return true
-		}
+func refusedDatabases(c client.Client, ns string) []reconcile.Request {
+	list := &dbv1beta1.DatabaseList{}
+	if err := c.List(context.TODO(), &client.ListOptions{Namespace: ns}, list); err != nil {
+		log.Error(err, "Unable to list Databases", "Namespace", ns)
+		return nil
 	}
-	return false
-}
 
-func remove(list []string, s string) []string {
-	for i, v := range list {
-		if v == s {
-			list = append(list[:i], list[i+1:]...)
+	var requests []reconcile.Request
+	for _, db := range list.Items {
+		if c := db.Status.GetCondition(dbv1beta1.DatabaseAdmitted); c != nil && c.Status == v1.ConditionFalse {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: db.Namespace, Name: db.Name}})
 		}
 	}
-	return list
+	return requests
 }
 
-func inWatchedNamespace(ns string) bool {
-	watchedNamespaces := strings.Split(os.Getenv("NAMESPACES"), ",")
+// pollsByRequeue reports if the Database waits for something nothing we watch reports on: its
+// expiry, the pre-drop dump of a deletion, a migration or a clone or restore Job
+func pollsByRequeue(db *dbv1beta1.Database) bool {
+	restoring := db.Status.Restore != nil && db.Status.Restore.Phase == dbv1beta1.RestoreRunning
+	return db.Spec.TTL != nil || db.GetDeletionTimestamp() != nil || db.Status.Migration != nil || cloning(db) || restoring
+}
 
-	return contains(watchedNamespaces, ns)
+func annotationChanged(old, new metav1.Object, key string) bool {
+	return old.GetAnnotations()[key] != new.GetAnnotations()[key]
 }
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPollsByRequeue(t *testing.T) {
+	now := metav1.Now()
+	for _, c := range []struct {
+		name string
+		edit func(db *dbv1beta1.Database)
+		want bool
+	}{
+		{"created", func(db *dbv1beta1.Database) {}, false},
+		{"ttl", func(db *dbv1beta1.Database) { db.Spec.TTL = &metav1.Duration{Duration: time.Hour} }, true},
+		{"being deleted", func(db *dbv1beta1.Database) { db.DeletionTimestamp = &now }, true},
+		{"migrating", func(db *dbv1beta1.Database) {
+			db.Status.Migration = &dbv1beta1.DatabaseMigrationStatus{Step: dbv1beta1.MigrationCopying}
+		}, true},
+		{"cloning", func(db *dbv1beta1.Database) {
+			db.Status.Clone = &dbv1beta1.DatabaseCloneStatus{Phase: dbv1beta1.RestoreRunning}
+		}, true},
+		{"cloned", func(db *dbv1beta1.Database) {
+			db.Status.Clone = &dbv1beta1.DatabaseCloneStatus{Phase: dbv1beta1.RestoreCompleted}
+		}, false},
+		{"restoring", func(db *dbv1beta1.Database) {
+			db.Status.Restore = &dbv1beta1.DatabaseRestoreStatus{Phase: dbv1beta1.RestoreRunning}
+		}, true},
+		{"restore failed", func(db *dbv1beta1.Database) {
+			db.Status.Restore = &dbv1beta1.DatabaseRestoreStatus{Phase: dbv1beta1.RestoreFailed}
+		}, false},
+	} {
+		db := &dbv1beta1.Database{Status: dbv1beta1.DatabaseStatus{Phase: "Created"}}
+		c.edit(db)
+		if got := pollsByRequeue(db); got != c.want {
+			t.Errorf("%s: pollsByRequeue = %t, want %t", c.name, got, c.want)
+		}
+	}
+}
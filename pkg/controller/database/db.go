@@ -1,10 +1,10 @@
 package database
 
 import (
-	dbv1alpha1 "db-operator/pkg/apis/db/v1alpha1"
+	"db-operator/pkg/apis/db/v1beta1"
 )
 
-func updateEvent(db *dbv1alpha1.Database, usr *user) error {
+func updateEvent(srv *server, db *v1beta1.Database, usr *user, ev eventer) error {
 	switch db.Spec.Type {
 	case "mysql":
 		log.Info("MySQL is not supported yet", "Db.Namespace", db.Namespace, "Db.Name", db.Name, "Db.Type", db.Spec.Type)
@@ -12,7 +12,7 @@ func updateEvent(db *dbv1alpha1.Database, usr *user) error {
 		break
 
 	case "postgres":
-		err := postgresUpdateEvent(db, usr)
+		err := srv.postgresUpdateEvent(db, usr, ev)
 		if err != nil {
 			log.Error(err, "Failed to create database", "Dbname:", db.Name)
 			return err
@@ -28,14 +28,15 @@ func updateEvent(db *dbv1alpha1.Database, usr *user) error {
 	return nil
 }
 
-func deleteEvent(db *dbv1alpha1.Database) error {
-	if db.Spec.Drop {
-		err := postgresDeleteEvent(db)
+func deleteEvent(srv *server, db *v1beta1.Database, ev eventer) error {
+	if db.Spec.DeletionPolicy == v1beta1.DeletionPolicyDelete {
+		err := srv.postgresDeleteEvent(db, ev)
 		if err != nil {
 			return err
 		}
 	} else {
-		log.Info("Database won't be deleted! Drop is set to false.")
+		log.Info("Database won't be dropped", "Database:", db.Name, "DeletionPolicy:", db.Spec.DeletionPolicy)
+		ev.normal(reasonRetained, "Database %s retained on server %s, deletion policy is %s", db.Name, srv.Name, v1beta1.DeletionPolicyRetain)
 	}
 	return nil
 }
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the Events recorded on Databases
+const (
+	reasonCreated         = "Created"
+	reasonUserCreated     = "UserCreated"
+	reasonGranted         = "Granted"
+	reasonRevoked         = "Revoked"
+	reasonSecretCreated   = "SecretCreated"
+	reasonDropped         = "Dropped"
+	reasonRetained        = "Retained"
+	reasonFailed          = "Failed"
+	reasonRetrying        = "Retrying"
+	reasonQuotaWarning    = "QuotaWarning"
+	reasonQuotaExceeded   = "QuotaExceeded"
+	reasonQuotaRestored   = "QuotaRestored"
+	reasonRefused         = "Refused"
+	reasonScheduled       = "Scheduled"
+	reasonMigrating       = "Migrating"
+	reasonMigrated        = "Migrated"
+	reasonBackupStarted   = "BackupStarted"
+	reasonBackedUp        = "BackedUp"
+	reasonBackupFailed    = "BackupFailed"
+	reasonRestoring       = "Restoring"
+	reasonRestored        = "Restored"
+	reasonRestoreFailed   = "RestoreFailed"
+	reasonCloning         = "Cloning"
+	reasonCloned          = "Cloned"
+	reasonCloneFailed     = "CloneFailed"
+	reasonMasked          = "Masked"
+	reasonExpired         = "Expired"
+	reasonTrashed         = "Trashed"
+	reasonUndeleted       = "Undeleted"
+	reasonDeletionBlocked = "DeletionBlocked"
+	reasonAltered         = "Altered"
+)
+
+// eventer records Events on a single object
+type eventer struct {
+	recorder record.EventRecorder
+	object   runtime.Object
+}
+
+func (e eventer) normal(reason, messageFmt string, args ...interface{}) {
+	e.recorder.Eventf(e.object, v1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+func (e eventer) warning(reason, messageFmt string, args ...interface{}) {
+	e.recorder.Eventf(e.object, v1.EventTypeWarning, reason, messageFmt, args...)
+}
+
+// failed records err as a Warning, with passwords removed from the message
+func (e eventer) failed(err error, secrets ...string) {
+	e.warning(reasonFailed, "%s", sanitize(err.Error(), secrets...))
+}
+
+var passwordPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(password\s+)'(?:[^']|'')*'`),
+	// Quoted values of connection strings may contain spaces and backslash escaped quotes
+	regexp.MustCompile(`(?i)(password\s*=\s*)'(?:[^'\\]|\\.)*'`),
+	regexp.MustCompile(`(?i)(password\s*=\s*)[^\s']\S*`),
+}
+
+// sanitize removes passwords from SQL statements and connection strings in msg,
+// along with any of the given secrets
+func sanitize(msg string, secrets ...string) string {
+	for _, p := range passwordPatterns {
+		msg = p.ReplaceAllString(msg, "${1}***")
+	}
+	for _, s := range secrets {
+		if s != "" {
+			msg = strings.Replace(msg, s, "***", -1)
+		}
+	}
+	return msg
+}
@@ -0,0 +1,44 @@
+package database
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	for _, c := range []struct {
+		msg, want string
+		secrets   []string
+	}{
+		{
+			msg:  `pq: syntax error at or near "x" in CREATE USER "app" WITH PASSWORD 'se''cret'`,
+			want: `pq: syntax error at or near "x" in CREATE USER "app" WITH PASSWORD ***`,
+		},
+		{
+			msg:  `dial failed: dbname='app' user='admin' password='top secret' host='db' port=5432 sslmode=disable`,
+			want: `dial failed: dbname='app' user='admin' password=*** host='db' port=5432 sslmode=disable`,
+		},
+		{
+			msg:  `dbname='app' password='it\'s secret' host='db'`,
+			want: `dbname='app' password=*** host='db'`,
+		},
+		{
+			msg:  `postgres://admin@db/app?sslmode=disable&password=secret&connect_timeout=5 is unreachable`,
+			want: `postgres://admin@db/app?sslmode=disable&password=*** is unreachable`,
+		},
+		{
+			msg:  `user=admin Password = secret host=db`,
+			want: `user=admin Password = *** host=db`,
+		},
+		{
+			msg:     `pg_dump: connection to "postgres://admin:hunter2@db/app" failed`,
+			want:    `pg_dump: connection to "postgres://admin:***@db/app" failed`,
+			secrets: []string{"", "hunter2"},
+		},
+		{
+			msg:  `database "app" does not exist`,
+			want: `database "app" does not exist`,
+		},
+	} {
+		if got := sanitize(c.msg, c.secrets...); got != c.want {
+			t.Errorf("sanitize(%q)\ngot  %q\nwant %q", c.msg, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"database/sql"
+	"db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/util"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func init() {
+	// Extensions shipped with postgres which don't give access to the server beyond the database
+	viper.SetDefault("allowedExtensions", []string{
+		"btree_gin",
+		"btree_gist",
+		"citext",
+		"cube",
+		"fuzzystrmatch",
+		"hstore",
+		"intarray",
+		"ltree",
+		"pg_trgm",
+		"pgcrypto",
+		"tablefunc",
+		"unaccent",
+		"uuid-ossp",
+	})
+}
+
+// allowsExtension reports if the operator may create the extension on the server.
+// Servers may replace the allowedExtensions of the operator configuration with their own.
+func (s *server) allowsExtension(name string) bool {
+	allowed := s.AllowedExtensions
+	if len(allowed) == 0 {
+		allowed = viper.GetStringSlice("allowedExtensions")
+	}
+	return util.Contains(allowed, name)
+}
+
+// postgresExtensions reads the extensions installed in the database
+func postgresExtensions(con *sql.DB) (map[string]v1beta1.DatabaseExtension, error) {
+	rows, err := con.Query(`SELECT e.extname, e.extversion, n.nspname FROM pg_extension e
+		JOIN pg_namespace n ON n.oid = e.extnamespace`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	installed := map[string]v1beta1.DatabaseExtension{}
+	for rows.Next() {
+		var e v1beta1.DatabaseExtension
+		if err := rows.Scan(&e.Name, &e.Version, &e.Schema); err != nil {
+			return nil, err
+		}
+		installed[e.Name] = e
+	}
+	return installed, rows.Err()
+}
+
+// adminSchema reports if the schema, the current schema if it's empty, is owned by the admin or a
+// superuser. Extension scripts run as the admin, objects of a tenant in their schema could take
+// them over. Missing schemas are left to the statement to report.
+func adminSchema(con *sql.DB, schema string) (bool, error) {
+	var owned bool
+	err := con.QueryRow(`SELECT r.rolsuper OR r.rolname = current_user OR (r.rolname = 'pg_database_owner' AND EXISTS (
+			SELECT 1 FROM pg_database d JOIN pg_roles o ON o.oid = d.datdba
+			WHERE d.datname = current_database() AND (o.rolsuper OR o.rolname = current_user)))
+		FROM pg_namespace n JOIN pg_roles r ON r.oid = n.nspowner
+		WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema())`, schema).Scan(&owned)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return owned, err
+}
+
+// extensionSchemas are the schemas, "" for the current one, the extension is created, updated
+// or moved in. They have to be owned by the admin, there are none if the extension is left alone.
+func extensionSchemas(e, current v1beta1.DatabaseExtension, installed bool) []string {
+	if !installed {
+		return []string{e.Schema}
+	}
+	var schemas []string
+	if e.Version != "" && e.Version != current.Version {
+		// Update scripts run in the schema of the extension
+		schemas = append(schemas, current.Schema)
+	}
+	if e.Schema != "" && e.Schema != current.Schema {
+		schemas = append(schemas, e.Schema)
+	}
+	return schemas
+}
+
+// postgresUpdateExtensions creates the allowed extensions in spec.extensions as the admin,
+// updates them to the requested version and schema and drops the ones removed from the spec.
+// Extensions aren't created, updated or moved in schemas of the tenant. The installed versions
+// are reported in the status.
+func (s *server) postgresUpdateExtensions(db *v1beta1.Database, ev eventer) error {
+	if len(db.Spec.Extensions) == 0 && len(db.Status.Extensions) == 0 {
+		return nil
+	}
+	// A copy of the database is about to replace the content, it brings its own extensions
+	if st := db.Status.Clone; st != nil && st.Phase == v1beta1.RestoreRunning {
+		return nil
+	}
+	if st := db.Status.Restore; st != nil && st.Phase == v1beta1.RestoreRunning {
+		return nil
+	}
+
+	con, err := s.connectTo(db.Name)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+
+	start := time.Now()
+	defer func() {
+		sqlDuration.WithLabelValues(s.Type, s.Name, opExtension).Observe(time.Since(start).Seconds())
+	}()
+
+	installed, err := postgresExtensions(con)
+	if err != nil {
+		log.Error(err, "Unable to read extensions", "Database:", db.Name)
+		return err
+	}
+
+	var managed, refused, tenantSchema, kept []string
+	for _, e := range db.Spec.Extensions {
+		if !s.allowsExtension(e.Name) {
+			refused = append(refused, e.Name)
+			continue
+		}
+		managed = append(managed, e.Name)
+
+		current, ok := installed[e.Name]
+		updating := ok && e.Version != "" && e.Version != current.Version
+		moving := ok && e.Schema != "" && e.Schema != current.Schema
+		owned := true
+		for _, schema := range extensionSchemas(e, current, ok) {
+			if owned, err = adminSchema(con, schema); err != nil || !owned {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if !owned {
+			tenantSchema = append(tenantSchema, e.Name)
+			continue
+		}
+
+		switch {
+		case !ok:
+			query := fmt.Sprintf(`CREATE EXTENSION IF NOT EXISTS "%s"`, e.Name)
+			if e.Schema != "" {
+				query += fmt.Sprintf(` SCHEMA "%s"`, e.Schema)
+			}
+			if e.Version != "" {
+				query += fmt.Sprintf(` VERSION %s`, pq.QuoteLiteral(e.Version))
+			}
+			if _, err := con.Exec(query); err != nil {
+				log.Error(err, "Unable to create extension", "Database:", db.Name, "Extension:", e.Name)
+				return err
+			}
+			ev.normal(reasonCreated, "Extension %s created", e.Name)
+			continue
+		case updating:
+			query := fmt.Sprintf(`ALTER EXTENSION "%s" UPDATE TO %s`, e.Name, pq.QuoteLiteral(e.Version))
+			if _, err := con.Exec(query); err != nil {
+				log.Error(err, "Unable to update extension", "Database:", db.Name, "Extension:", e.Name)
+				return err
+			}
+			ev.normal(reasonAltered, "Extension %s updated from %s to %s", e.Name, current.Version, e.Version)
+		}
+		if moving {
+			query := fmt.Sprintf(`ALTER EXTENSION "%s" SET SCHEMA "%s"`, e.Name, e.Schema)
+			if _, err := con.Exec(query); err != nil {
+				log.Error(err, "Unable to move extension", "Database:", db.Name, "Extension:", e.Name)
+				return err
+			}
+			ev.normal(reasonAltered, "Extension %s moved to schema %s", e.Name, e.Schema)
+		}
+	}
+
+	// Objects depending on an extension keep it, it's dropped once they're gone
+	for _, e := range db.Status.Extensions {
+		if util.Contains(managed, e.Name) {
+			continue
+		}
+		if _, err := con.Exec(fmt.Sprintf(`DROP EXTENSION IF EXISTS "%s"`, e.Name)); err != nil {
+			log.Error(err, "Unable to drop extension", "Database:", db.Name, "Extension:", e.Name)
+			kept = append(kept, e.Name)
+			continue
+		}
+		ev.normal(reasonDropped, "Extension %s dropped", e.Name)
+	}
+
+	if installed, err = postgresExtensions(con); err != nil {
+		return err
+	}
+	db.Status.Extensions = nil
+	for _, name := range append(managed, kept...) {
+		if e, ok := installed[name]; ok {
+			db.Status.Extensions = append(db.Status.Extensions, e)
+		}
+	}
+
+	var problems []string
+	if len(refused) > 0 {
+		problems = append(problems, fmt.Sprintf("Extensions %s may not be created on server %s", strings.Join(refused, ", "), s.Name))
+	}
+	if len(tenantSchema) > 0 {
+		problems = append(problems, fmt.Sprintf("Extensions %s can't be created, updated or moved in schemas the operator doesn't own",
+			strings.Join(tenantSchema, ", ")))
+	}
+	if len(kept) > 0 {
+		problems = append(problems, fmt.Sprintf("Extensions %s can't be dropped, other objects depend on them", strings.Join(kept, ", ")))
+	}
+	if len(problems) > 0 {
+		msg := strings.Join(problems, ". ")
+		if c := db.Status.GetCondition(v1beta1.DatabaseExtensionsApplied); c == nil || c.Message != msg {
+			ev.warning(reasonRefused, "%s", msg)
+		}
+		reason := "ExtensionNotAllowed"
+		switch {
+		case len(refused) > 0:
+		case len(tenantSchema) > 0:
+			reason = "SchemaNotAllowed"
+		default:
+			reason = "DropFailed"
+		}
+		db.Status.SetCondition(v1beta1.DatabaseExtensionsApplied, corev1.ConditionFalse, reason, msg)
+	} else {
+		db.Status.SetCondition(v1beta1.DatabaseExtensionsApplied, corev1.ConditionTrue, "Applied", "")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"db-operator/pkg/apis/db/v1beta1"
+)
+
+func TestAllowsExtension(t *testing.T) {
+	defaults := &server{Name: "db1"}
+	restricted := &server{Name: "db2", AllowedExtensions: []string{"postgis", "citext"}}
+
+	for _, c := range []struct {
+		srv  *server
+		name string
+		want bool
+	}{
+		{defaults, "pgcrypto", true},
+		{defaults, "uuid-ossp", true},
+		{defaults, "postgis", false},
+		{defaults, "plpython3u", false},
+		{defaults, "adminpack", false},
+		{defaults, "PGCRYPTO", false},
+		{restricted, "postgis", true},
+		{restricted, "citext", true},
+		{restricted, "pgcrypto", false},
+	} {
+		if got := c.srv.allowsExtension(c.name); got != c.want {
+			t.Errorf("%s: allowsExtension(%s) = %t, want %t", c.srv.Name, c.name, got, c.want)
+		}
+	}
+}
+
+func TestExtensionSchemas(t *testing.T) {
+	installed := v1beta1.DatabaseExtension{Name: "citext", Version: "1.5", Schema: "public"}
+
+	for _, c := range []struct {
+		name      string
+		e         v1beta1.DatabaseExtension
+		installed bool
+		want      []string
+	}{
+		{"create in the current schema", v1beta1.DatabaseExtension{Name: "citext"}, false, []string{""}},
+		{"create in a schema", v1beta1.DatabaseExtension{Name: "citext", Schema: "ext"}, false, []string{"ext"}},
+		{"unchanged", v1beta1.DatabaseExtension{Name: "citext"}, true, nil},
+		{"same version and schema", v1beta1.DatabaseExtension{Name: "citext", Version: "1.5", Schema: "public"}, true, nil},
+		{"update", v1beta1.DatabaseExtension{Name: "citext", Version: "1.6"}, true, []string{"public"}},
+		{"move", v1beta1.DatabaseExtension{Name: "citext", Schema: "ext"}, true, []string{"ext"}},
+		{"update and move", v1beta1.DatabaseExtension{Name: "citext", Version: "1.6", Schema: "ext"}, true, []string{"public", "ext"}},
+	} {
+		if got := extensionSchemas(c.e, installed, c.installed); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: extensionSchemas = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
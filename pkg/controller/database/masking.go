@@ -0,0 +1,117 @@
+package database
+
+import (
+	"db-operator/pkg/apis/db/v1beta1"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Masking actions of a masking profile
+const (
+	maskNullify   = "nullify"
+	maskHash      = "hash"
+	maskFakeEmail = "fakeEmail"
+	maskFixed     = "fixed:"
+)
+
+// maskedColumn is a column of a masking profile with the expression replacing its values
+type maskedColumn struct {
+	name       string
+	expression string
+}
+
+// maskingProfile maps schema.table to its masked columns
+type maskingProfile map[string][]maskedColumn
+
+// parseMaskingProfile reads the rules of a masking profile ConfigMap,
+// keys are [schema.]table.column and values the masking action
+func parseMaskingProfile(data map[string]string) (maskingProfile, error) {
+	profile := maskingProfile{}
+	for key, action := range data {
+		parts := strings.Split(key, ".")
+		switch len(parts) {
+		case 2:
+			parts = append([]string{"public"}, parts...)
+		case 3:
+		default:
+			return nil, fmt.Errorf("masking rule %s: expected [schema.]table.column", key)
+		}
+
+		column := pq.QuoteIdentifier(parts[2])
+		var expression string
+		switch action = strings.TrimSpace(action); {
+		case action == maskNullify:
+			expression = "NULL"
+		case action == maskHash:
+			expression = fmt.Sprintf("md5(%s::text)", column)
+		case action == maskFakeEmail:
+			expression = fmt.Sprintf("'user_' || left(md5(%[1]s::text), 12) || '@example.com'", column)
+		case strings.HasPrefix(action, maskFixed):
+			expression = pq.QuoteLiteral(strings.TrimPrefix(action, maskFixed))
+		default:
+			return nil, fmt.Errorf("masking rule %s: unknown action %q, expected %s, %s, %s or %s<value>",
+				key, action, maskNullify, maskHash, maskFakeEmail, maskFixed)
+		}
+
+		table := parts[0] + "." + parts[1]
+		profile[table] = append(profile[table], maskedColumn{name: parts[2], expression: expression})
+	}
+	return profile, nil
+}
+
+// postgresMask applies the masking profile to database in one transaction, each table is
+// rewritten by a single UPDATE. Values which are NULL stay NULL.
+func (s *server) postgresMask(database string, profile maskingProfile) ([]v1beta1.MaskedTable, error) {
+	tables := make([]string, 0, len(profile))
+	for table := range profile {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	con, err := s.connectTo(database)
+	if err != nil {
+		return nil, err
+	}
+	defer con.Close()
+
+	start := time.Now()
+	defer func() {
+		sqlDuration.WithLabelValues(s.Type, s.Name, opMask).Observe(time.Since(start).Seconds())
+	}()
+
+	tx, err := con.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := make([]v1beta1.MaskedTable, 0, len(tables))
+	for _, table := range tables {
+		columns := profile[table]
+		sort.Slice(columns, func(i, j int) bool { return columns[i].name < columns[j].name })
+
+		names := make([]string, 0, len(columns))
+		sets := make([]string, 0, len(columns))
+		for _, c := range columns {
+			names = append(names, c.name)
+			column := pq.QuoteIdentifier(c.name)
+			sets = append(sets, fmt.Sprintf("%[1]s = CASE WHEN %[1]s IS NULL THEN NULL ELSE %[2]s END", column, c.expression))
+		}
+		parts := strings.SplitN(table, ".", 2)
+		query := fmt.Sprintf(`UPDATE %s.%s SET %s`, pq.QuoteIdentifier(parts[0]), pq.QuoteIdentifier(parts[1]), strings.Join(sets, ", "))
+
+		res, err := tx.Exec(query)
+		if err != nil {
+			log.Error(err, "Unable to mask table", "Database:", database, "Table:", table)
+			return nil, fmt.Errorf("masking %s: %s", table, err)
+		}
+		rows, _ := res.RowsAffected()
+		report = append(report, v1beta1.MaskedTable{Table: table, Columns: names, Rows: rows})
+	}
+
+	return report, tx.Commit()
+}
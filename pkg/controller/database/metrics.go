@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// SQL operations timed by the sql duration histogram
+const (
+	opCreateDB   = "create_db"
+	opCreateUser = "create_user"
+	opGrant      = "grant"
+	opRevoke     = "revoke"
+	opDrop       = "drop"
+	opUsage      = "usage"
+	opMask       = "mask"
+	opAlter      = "alter"
+	opSchema     = "schema"
+	opExtension  = "extension"
+)
+
+var (
+	databasesCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_operator_databases_created_total",
+		Help: "Number of databases created by the operator",
+	}, []string{"engine", "server"})
+
+	databasesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_operator_databases_dropped_total",
+		Help: "Number of databases dropped by the operator",
+	}, []string{"engine", "server"})
+
+	databasesFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_operator_database_failures_total",
+		Help: "Number of failed Database reconciles",
+	}, []string{"engine", "server"})
+
+	sqlDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_operator_sql_duration_seconds",
+		Help:    "Latency of the SQL statements run by the operator",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"engine", "server", "operation"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(databasesCreated, databasesDropped, databasesFailed, sqlDuration, &poolCollector{})
+}
+
+// exec runs query on the admin connection and records its latency as operation
+func (s *server) exec(operation, query string) (sql.Result, error) {
+	start := time.Now()
+	res, err := s.con.Exec(query)
+	sqlDuration.WithLabelValues(s.Type, s.Name, operation).Observe(time.Since(start).Seconds())
+
+	return res, err
+}
+
+var managedDatabasesDesc = prometheus.NewDesc(
+	"db_operator_managed_databases",
+	"Number of Databases managed by the operator",
+	[]string{"engine", "server", "phase"}, nil,
+)
+
+// managedCollector counts the Databases in the manager cache on every scrape
+type managedCollector struct {
+	client client.Client
+}
+
+func (c *managedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- managedDatabasesDesc
+}
+
+func (c *managedCollector) Collect(ch chan<- prometheus.Metric) {
+	list := &dbv1beta1.DatabaseList{}
+	if err := c.client.List(context.TODO(), &client.ListOptions{}, list); err != nil {
+		log.Error(err, "Unable to list Databases for metrics")
+		return
+	}
+
+	type key struct{ engine, server, phase string }
+	counts := map[key]int{}
+	for i := range list.Items {
+		db := &list.Items[i]
+		counts[key{db.Spec.Type, serverName(db), db.Status.Phase}]++
+	}
+	for k, n := range counts {
+		ch <- prometheus.MustNewConstMetric(managedDatabasesDesc, prometheus.GaugeValue, float64(n), k.engine, k.server, k.phase)
+	}
+}
+
+var (
+	poolOpenDesc = prometheus.NewDesc(
+		"db_operator_server_connections",
+		"Admin connections to the database server by state",
+		[]string{"engine", "server", "state"}, nil,
+	)
+	poolMaxOpenDesc = prometheus.NewDesc(
+		"db_operator_server_connections_max",
+		"Maximum number of open admin connections to the database server",
+		[]string{"engine", "server"}, nil,
+	)
+	poolWaitCountDesc = prometheus.NewDesc(
+		"db_operator_server_connection_waits_total",
+		"Number of times the operator waited for a free admin connection",
+		[]string{"engine", "server"}, nil,
+	)
+	poolWaitDurationDesc = prometheus.NewDesc(
+		"db_operator_server_connection_wait_seconds_total",
+		"Time spent waiting for a free admin connection",
+		[]string{"engine", "server"}, nil,
+	)
+)
+
+// poolCollector exposes the admin connection pool stats of every server
+type poolCollector struct{}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolOpenDesc
+	ch <- poolMaxOpenDesc
+	ch <- poolWaitCountDesc
+	ch <- poolWaitDurationDesc
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range servers {
+		if s.con == nil {
+			continue
+		}
+		stats := s.con.Stats()
+		ch <- prometheus.MustNewConstMetric(poolOpenDesc, prometheus.GaugeValue, float64(stats.InUse), s.Type, s.Name, "in_use")
+		ch <- prometheus.MustNewConstMetric(poolOpenDesc, prometheus.GaugeValue, float64(stats.Idle), s.Type, s.Name, "idle")
+		ch <- prometheus.MustNewConstMetric(poolMaxOpenDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections), s.Type, s.Name)
+		ch <- prometheus.MustNewConstMetric(poolWaitCountDesc, prometheus.CounterValue, float64(stats.WaitCount), s.Type, s.Name)
+		ch <- prometheus.MustNewConstMetric(poolWaitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds(), s.Type, s.Name)
+	}
+}
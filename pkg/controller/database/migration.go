@@ -0,0 +1,395 @@
+package database
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/jobs"
+	"db-operator/pkg/quota"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func init() {
+	viper.SetDefault("migrationRetention", "24h")
+}
+
+// syncPollInterval is how often the target is checked while writes on the source are blocked
+const syncPollInterval = 2 * time.Second
+
+// copyScript streams the source database into the target database, with extra pg_dump options.
+// Ownership and grants are left out, the objects are handed to the _owners role after the restore.
+const copyScript = `pg_dump --format=custom --no-owner --no-acl %s --dbname="$SOURCE" | ` +
+	`pg_restore --no-owner --no-acl --exit-on-error --dbname="$TARGET"`
+
+// migrate moves a provisioned database to the server in spec.serverRef:
+//  1. Preparing: the database, its user with the current password and the _owners role are
+//     created on the target
+//  2. Copying: a Job copies the schema, then the target subscribes to all tables of the source
+//     and copies the data while the database is in use
+//  3. Syncing: access to the source is revoked until the target replayed the last changes, the
+//     sequences are copied and the subscription is dropped
+//  4. the objects are handed to the _owners role, the Secret points to the target and the
+//     database is pinned to it
+//  5. Draining: the source is kept for migrationRetention in case something went wrong, then dropped
+//
+// done is true if the rest of Reconcile must not run, e.g. while data is copied.
+func (r *ReconcileDatabase) migrate(reqLogger logr.Logger, db *dbv1beta1.Database, ev eventer) (result reconcile.Result, done bool, err error) {
+	m := db.Status.Migration
+	if m == nil {
+		if db.Status.Phase != "Created" || db.Spec.ServerRef == nil || db.Spec.ServerRef.Name == serverName(db) {
+			return reconcile.Result{}, false, nil
+		}
+		if err := r.startMigration(reqLogger, db, ev); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		m = db.Status.Migration
+	}
+
+	source, target := servers[m.Source], servers[m.Target]
+	if source == nil || target == nil {
+		result, err = r.failed(db, ev, fmt.Errorf("migration from %s to %s: server is not configured", m.Source, m.Target))
+		return result, true, err
+	}
+
+	// Setting serverRef back to the source cancels the migration until the switch
+	if m.Step != dbv1beta1.MigrationDraining && (db.Spec.ServerRef == nil || db.Spec.ServerRef.Name == m.Source) {
+		if err := r.cancelMigration(db, ev); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		return reconcile.Result{}, false, nil
+	}
+
+	switch m.Step {
+	case dbv1beta1.MigrationPreparing:
+		if err := r.prepareTarget(db, target); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		m.Step = dbv1beta1.MigrationCopying
+		db.Status.SetCondition(dbv1beta1.DatabaseMigrating, v1.ConditionTrue, string(m.Step),
+			fmt.Sprintf("Copying to server %s", m.Target))
+		fallthrough
+
+	case dbv1beta1.MigrationCopying:
+		state, err := r.jobs.Run(jobs.Name("copy", db), db, copyJob(db, source, target, "--schema-only --no-publications --no-subscriptions"))
+		if err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		switch state {
+		case jobs.Running:
+			return reconcile.Result{RequeueAfter: jobs.PollInterval}, true, r.client.Status().Update(context.TODO(), db)
+		case jobs.Failed:
+			return reconcile.Result{}, true, r.failMigration(db, ev, "CopyFailed", "copying the schema failed")
+		}
+
+		if err := source.postgresPublish(db.Name); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		if err := target.postgresSubscribe(db.Name, source); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		synced, _, err := target.postgresSubscriptionSynced(db.Name)
+		if err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		if !synced {
+			return reconcile.Result{RequeueAfter: jobs.PollInterval}, true, r.client.Status().Update(context.TODO(), db)
+		}
+
+		if err := source.postgresRevokeAccess(db.Name); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		// Nothing but the admin writes to the source from here on
+		if m.SyncLSN, err = source.postgresCurrentLSN(); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		ev.normal(reasonMigrating, "Writes on server %s are blocked for the final sync", m.Source)
+		m.Step = dbv1beta1.MigrationSyncing
+		db.Status.SetCondition(dbv1beta1.DatabaseMigrating, v1.ConditionTrue, string(m.Step),
+			fmt.Sprintf("Final sync to server %s, access to the database is blocked", m.Target))
+		fallthrough
+
+	case dbv1beta1.MigrationSyncing:
+		// Tables created after the subscription aren't replicated
+		published, err := source.postgresPublishedTables(db.Name)
+		if err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		_, subscribed, err := target.postgresSubscriptionSynced(db.Name)
+		if err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		if published != subscribed {
+			if err := source.postgresRestoreAccess(db.Name); err != nil {
+				result, err = r.failed(db, ev, err)
+				return result, true, err
+			}
+			return reconcile.Result{}, true, r.failMigration(db, ev, "SyncFailed",
+				"tables were created during the migration, access to the source is restored")
+		}
+		caughtUp, err := source.postgresCaughtUp(db.Name, m.SyncLSN)
+		if err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		if !caughtUp {
+			return reconcile.Result{RequeueAfter: syncPollInterval}, true, r.client.Status().Update(context.TODO(), db)
+		}
+		if err := source.postgresCopySequences(db.Name, target); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		if err := target.postgresUnsubscribe(db.Name); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		if err := source.postgresUnpublish(db.Name); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+
+		if err := r.switchServer(db, target); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		if err := r.jobs.Cleanup(jobs.Name("copy", db)); err != nil {
+			reqLogger.Error(err, "Unable to clean up migration Job", "Job", jobs.Name("copy", db))
+		}
+
+		dropAfter := metav1.NewTime(time.Now().Add(viper.GetDuration("migrationRetention")))
+		m.Step = dbv1beta1.MigrationDraining
+		m.DropSourceAfter = &dropAfter
+		db.Status.SetCondition(dbv1beta1.DatabaseMigrating, v1.ConditionFalse, "Completed",
+			fmt.Sprintf("Moved from server %s, the source is dropped after %s", m.Source, dropAfter.UTC().Format(time.RFC3339)))
+		ev.normal(reasonMigrated, "Database moved from server %s to %s", m.Source, m.Target)
+		return reconcile.Result{}, false, nil
+
+	case dbv1beta1.MigrationDraining:
+		if m.DropSourceAfter != nil && time.Now().Before(m.DropSourceAfter.Time) {
+			return reconcile.Result{}, false, nil
+		}
+		if err := source.postgresDropCopy(db, ev); err != nil {
+			result, err = r.failed(db, ev, err)
+			return result, true, err
+		}
+		db.Status.Migration = nil
+		return reconcile.Result{}, false, nil
+
+	default:
+		// Failed, waiting for serverRef to be set back
+		return reconcile.Result{}, true, nil
+	}
+}
+
+// requeueMigration returns when the database has to be reconciled again to drop the migration source
+func requeueMigration(db *dbv1beta1.Database) reconcile.Result {
+	m := db.Status.Migration
+	if m == nil || m.DropSourceAfter == nil {
+		return reconcile.Result{}
+	}
+	return reconcile.Result{RequeueAfter: time.Until(m.DropSourceAfter.Time)}
+}
+
+func (r *ReconcileDatabase) startMigration(reqLogger logr.Logger, db *dbv1beta1.Database, ev eventer) error {
+	sourceName, targetName := serverName(db), db.Spec.ServerRef.Name
+	source, ok := servers[sourceName]
+	if !ok {
+		return fmt.Errorf("server %s is not configured", sourceName)
+	}
+	target, ok := servers[targetName]
+	if !ok {
+		return fmt.Errorf("server %s is not configured", targetName)
+	}
+	if target.Type != db.Spec.Type {
+		return fmt.Errorf("server %s is not a %s server", targetName, db.Spec.Type)
+	}
+
+	ns := &v1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: db.Namespace}, ns); err != nil {
+		return err
+	}
+	if !target.allows(ns) {
+		return fmt.Errorf("namespace %s is not allowed to use server %s", db.Namespace, targetName)
+	}
+	// Passing db as the old object only checks the allowed servers
+	if err := quota.Admit(r.client, db, db, targetName); err != nil {
+		return err
+	}
+
+	if err := source.postgresCheckPublish(db.Name); err != nil {
+		return err
+	}
+	// Whatever exists on the target after this check was created by the migration
+	for _, name := range []string{db.Name, fmt.Sprintf(`%s_owners`, db.Name)} {
+		if exists, err := target.postgresRoleExists(name); err != nil {
+			return err
+		} else if exists {
+			return fmt.Errorf("role %s already exists on server %s", name, targetName)
+		}
+	}
+	if exists, err := target.postgresExists(db.Name); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("database %s already exists on server %s", db.Name, targetName)
+	}
+
+	reqLogger.Info("Migrating Database", "Source", sourceName, "Target", targetName)
+	db.Status.Migration = &dbv1beta1.DatabaseMigrationStatus{
+		Source:    sourceName,
+		Target:    targetName,
+		Step:      dbv1beta1.MigrationPreparing,
+		StartTime: metav1.Now(),
+	}
+	db.Status.SetCondition(dbv1beta1.DatabaseMigrating, v1.ConditionTrue, string(dbv1beta1.MigrationPreparing),
+		fmt.Sprintf("Preparing server %s", targetName))
+	ev.normal(reasonMigrating, "Moving database from server %s to %s", sourceName, targetName)
+	// Recorded before anything is created, so that a failed attempt is retried or cleaned up
+	return r.client.Status().Update(context.TODO(), db)
+}
+
+// prepareTarget creates the database, its user and the _owners role on the target unless a
+// previous attempt did already
+func (r *ReconcileDatabase) prepareTarget(db *dbv1beta1.Database, target *server) error {
+	// The user keeps its password, only the host in the Secret changes
+	secret := &v1.Secret{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: db.Namespace, Name: secretName(db)}, secret); err != nil {
+		return err
+	}
+	usr := &user{username: db.Name, password: string(secret.Data["database-password"])}
+
+	exists, err := target.postgresExists(db.Name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := target.postgresCreateDB(db.Name, "", db.Spec.Settings, false); err != nil {
+			return err
+		}
+	}
+	if exists, err = target.postgresRoleExists(usr.username); err != nil {
+		return err
+	}
+	if !exists {
+		if err := target.postgresCreateUser(db, usr); err != nil {
+			return err
+		}
+	}
+	users := append(db.Spec.UserNames(), usr.username)
+	if exists, err = target.postgresRoleExists(fmt.Sprintf(`%s_owners`, db.Name)); err != nil {
+		return err
+	}
+	if !exists {
+		return target.postgresGrantAllWithRole(users, db.Name)
+	}
+	if err := target.postgresRestoreAccess(db.Name); err != nil {
+		return err
+	}
+	return target.postgresGrantAll(users, fmt.Sprintf(`%s_owners`, db.Name))
+}
+
+// switchServer points the database and its Secret to the target server
+func (r *ReconcileDatabase) switchServer(db *dbv1beta1.Database, target *server) error {
+	if err := target.postgresOwnObjects(db.Name); err != nil {
+		return err
+	}
+
+	secret := &v1.Secret{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: db.Namespace, Name: secretName(db)}, secret); err != nil {
+		return err
+	}
+	secret.Data["database-host"] = []byte(target.Host)
+	secret.Data["database-port"] = []byte(strconv.Itoa(target.Port))
+	if err := r.client.Update(context.TODO(), secret); err != nil {
+		return err
+	}
+
+	db.Status.Server = target.Name
+	return nil
+}
+
+// cancelMigration drops the copy on the target and gives access to the source back
+func (r *ReconcileDatabase) cancelMigration(db *dbv1beta1.Database, ev eventer) error {
+	m := db.Status.Migration
+	if err := r.cleanupMigration(db, ev); err != nil {
+		return err
+	}
+	if m.Step == dbv1beta1.MigrationSyncing {
+		if err := servers[m.Source].postgresRestoreAccess(db.Name); err != nil {
+			return err
+		}
+	}
+
+	db.Status.Migration = nil
+	db.Status.SetCondition(dbv1beta1.DatabaseMigrating, v1.ConditionFalse, "Cancelled", "")
+	ev.normal(reasonMigrating, "Migration to server %s cancelled", m.Target)
+	return nil
+}
+
+// cleanupMigration removes the Job, the replication and whichever copy of the database isn't
+// in use, the target before the switch and the source afterwards
+func (r *ReconcileDatabase) cleanupMigration(db *dbv1beta1.Database, ev eventer) error {
+	m := db.Status.Migration
+	if err := r.jobs.Cleanup(jobs.Name("copy", db)); err != nil {
+		return err
+	}
+	if m.Step != dbv1beta1.MigrationDraining {
+		if target, ok := servers[m.Target]; ok {
+			if err := target.postgresUnsubscribe(db.Name); err != nil {
+				return err
+			}
+		}
+		if source, ok := servers[m.Source]; ok {
+			if err := source.postgresUnpublish(db.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	unused := m.Target
+	if m.Step == dbv1beta1.MigrationDraining {
+		unused = m.Source
+	}
+	srv, ok := servers[unused]
+	if !ok {
+		return fmt.Errorf("server %s is not configured", unused)
+	}
+	return srv.postgresDropCopy(db, ev)
+}
+
+func (r *ReconcileDatabase) failMigration(db *dbv1beta1.Database, ev eventer, reason, message string) error {
+	m := db.Status.Migration
+	m.Step = dbv1beta1.MigrationFailed
+	ev.warning(reasonFailed, "Migration to server %s failed: %s", m.Target, message)
+	db.Status.SetCondition(dbv1beta1.DatabaseMigrating, v1.ConditionFalse, reason,
+		fmt.Sprintf("%s, set spec.serverRef back to %s to cancel", message, m.Source))
+	return r.client.Status().Update(context.TODO(), db)
+}
+
+// copyJob copies the database from source to target, with extra pg_dump options
+func copyJob(db *dbv1beta1.Database, source, target *server, dumpOptions string) jobs.Spec {
+	return jobs.Spec{
+		Steps: []jobs.Step{{Script: fmt.Sprintf(copyScript, dumpOptions)}},
+		Env: map[string]string{
+			"SOURCE": source.dsn(db.Name),
+			"TARGET": target.dsn(db.Name),
+		},
+	}
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/util"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func init() {
+	// Parameters any user may set for the session, none of them needs superuser rights
+	viper.SetDefault("allowedParameters", []string{
+		"statement_timeout",
+		"lock_timeout",
+		"idle_in_transaction_session_timeout",
+		"search_path",
+		"timezone",
+		"datestyle",
+		"intervalstyle",
+		"work_mem",
+		"temp_buffers",
+		"default_transaction_isolation",
+		"default_transaction_read_only",
+		"random_page_cost",
+		"jit",
+	})
+}
+
+var parameterName = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// listParameters take a list of values, each of them is quoted separately
+var listParameters = []string{"search_path", "temp_tablespaces", "local_preload_libraries", "session_preload_libraries"}
+
+// allowsParameter reports if the operator may set the runtime parameter on the server.
+// Servers may replace the allowedParameters of the operator configuration with their own.
+func (s *server) allowsParameter(name string) bool {
+	allowed := s.AllowedParameters
+	if len(allowed) == 0 {
+		allowed = viper.GetStringSlice("allowedParameters")
+	}
+	return parameterName.MatchString(name) && util.Contains(allowed, strings.ToLower(name))
+}
+
+// parameterValue is the value as postgres reports it in pg_db_role_setting
+func parameterValue(name, value string) string {
+	if !util.Contains(listParameters, name) {
+		return value
+	}
+	items := strings.Split(value, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return strings.Join(items, ", ")
+}
+
+// parameterLiteral is the value quoted for ALTER DATABASE SET
+func parameterLiteral(name, value string) string {
+	if !util.Contains(listParameters, name) {
+		return pq.QuoteLiteral(value)
+	}
+	items := strings.Split(value, ",")
+	for i := range items {
+		items[i] = pq.QuoteLiteral(strings.TrimSpace(items[i]))
+	}
+	return strings.Join(items, ", ")
+}
+
+// postgresParameters reads the runtime parameters set in database by ALTER DATABASE SET and
+// ALTER ROLE IN DATABASE SET. They're mapped by role, the ones of the database by "".
+func (s *server) postgresParameters(database string) (map[string]map[string]string, error) {
+	query := `SELECT COALESCE(r.rolname, ''), unnest(s.setconfig) FROM pg_db_role_setting s
+		JOIN pg_database d ON d.oid = s.setdatabase
+		LEFT JOIN pg_roles r ON r.oid = s.setrole
+		WHERE d.datname = $1`
+	rows, err := s.con.Query(query, database)
+	if err != nil {
+		log.Error(err, "Unable to read database parameters", "Database:", database)
+		return nil, err
+	}
+	defer rows.Close()
+
+	current := map[string]map[string]string{}
+	for rows.Next() {
+		var role, setting string
+		if err := rows.Scan(&role, &setting); err != nil {
+			return nil, err
+		}
+		if current[role] == nil {
+			current[role] = map[string]string{}
+		}
+		if parts := strings.SplitN(setting, "=", 2); len(parts) == 2 {
+			current[role][strings.ToLower(parts[0])] = parts[1]
+		}
+	}
+	return current, rows.Err()
+}
+
+// postgresApplyParameters sets the allowed spec.parameters which differ from the ones on the
+// server and resets the parameters it set before which were removed from spec.parameters.
+// Parameters set outside of spec.parameters are left alone.
+func (s *server) postgresApplyParameters(db *v1beta1.Database, ev eventer) error {
+	if len(db.Spec.Parameters) == 0 && len(db.Status.Parameters) == 0 &&
+		db.Status.GetCondition(v1beta1.DatabaseParametersApplied) == nil {
+		return nil
+	}
+
+	parameters, err := s.postgresParameters(db.Name)
+	if err != nil {
+		return err
+	}
+	current := parameters[""]
+
+	names := make([]string, 0, len(db.Spec.Parameters))
+	for name := range db.Spec.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	applied := map[string]string{}
+	var refused []string
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if !s.allowsParameter(key) {
+			refused = append(refused, name)
+			continue
+		}
+		value := parameterValue(key, db.Spec.Parameters[name])
+		applied[key] = value
+
+		was, set := current[key]
+		if set && was == value {
+			continue
+		}
+		query := fmt.Sprintf(`ALTER DATABASE "%s" SET %s = %s`, db.Name, key, parameterLiteral(key, value))
+		if _, err := s.exec(opAlter, query); err != nil {
+			return err
+		}
+		if previous, ok := db.Status.Parameters[key]; ok && previous == value {
+			ev.warning(reasonAltered, "Parameter %s was changed to %q outside of the operator, set it back to %q", key, was, value)
+		} else {
+			ev.normal(reasonAltered, "Parameter %s set to %q", key, value)
+		}
+	}
+
+	for key := range db.Status.Parameters {
+		if _, ok := applied[key]; ok {
+			continue
+		}
+		if _, set := current[key]; set {
+			query := fmt.Sprintf(`ALTER DATABASE "%s" RESET %s`, db.Name, key)
+			if _, err := s.exec(opAlter, query); err != nil {
+				return err
+			}
+			ev.normal(reasonAltered, "Parameter %s reset", key)
+		}
+	}
+
+	if len(applied) == 0 {
+		applied = nil
+	}
+	db.Status.Parameters = applied
+	if len(refused) > 0 {
+		msg := fmt.Sprintf("Parameters %s may not be set on server %s", strings.Join(refused, ", "), s.Name)
+		if c := db.Status.GetCondition(v1beta1.DatabaseParametersApplied); c == nil || c.Message != msg {
+			ev.warning(reasonRefused, "%s", msg)
+		}
+		db.Status.SetCondition(v1beta1.DatabaseParametersApplied, corev1.ConditionFalse, "ParameterNotAllowed", msg)
+	} else {
+		db.Status.SetCondition(v1beta1.DatabaseParametersApplied, corev1.ConditionTrue, "Applied", "")
+	}
+	return nil
+}
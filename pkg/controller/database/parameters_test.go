@@ -0,0 +1,52 @@
+package database
+
+import "testing"
+
+func TestAllowsParameter(t *testing.T) {
+	defaults := &server{Name: "db1"}
+	restricted := &server{Name: "db2", AllowedParameters: []string{"work_mem", "pg_stat_statements.track"}}
+
+	for _, c := range []struct {
+		srv  *server
+		name string
+		want bool
+	}{
+		{defaults, "statement_timeout", true},
+		{defaults, "search_path", true},
+		{defaults, "shared_buffers", false},
+		{defaults, "session_preload_libraries", false},
+		{defaults, "work_mem = 1; ALTER ROLE app SUPERUSER", false},
+		{restricted, "work_mem", true},
+		{restricted, "statement_timeout", false},
+		{restricted, "pg_stat_statements.track", true},
+		{restricted, "pg_stat_statements.track.all", false},
+		{restricted, "work_mem ", false},
+	} {
+		if got := c.srv.allowsParameter(c.name); got != c.want {
+			t.Errorf("%s: allowsParameter(%q) = %t, want %t", c.srv.Name, c.name, got, c.want)
+		}
+	}
+}
+
+func TestParameterValueAndLiteral(t *testing.T) {
+	for _, c := range []struct {
+		name, value, normalized, literal string
+	}{
+		{"work_mem", "64MB", "64MB", `'64MB'`},
+		{"timezone", "Europe/Berlin", "Europe/Berlin", `'Europe/Berlin'`},
+		{"statement_timeout", "30s'; DROP TABLE x; --", "30s'; DROP TABLE x; --", `'30s''; DROP TABLE x; --'`},
+		// Values of other parameters may contain commas
+		{"datestyle", "ISO, MDY", "ISO, MDY", `'ISO, MDY'`},
+		{"search_path", "app", "app", `'app'`},
+		{"search_path", "app,public", "app, public", `'app', 'public'`},
+		{"search_path", ` "$user" ,  public`, `"$user", public`, `'"$user"', 'public'`},
+		{"search_path", "it's,public", "it's, public", `'it''s', 'public'`},
+	} {
+		if got := parameterValue(c.name, c.value); got != c.normalized {
+			t.Errorf("parameterValue(%s, %q) = %q, want %q", c.name, c.value, got, c.normalized)
+		}
+		if got := parameterLiteral(c.name, c.value); got != c.literal {
+			t.Errorf("parameterLiteral(%s, %q) = %s, want %s", c.name, c.value, got, c.literal)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/quota"
+	"db-operator/pkg/util"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxScore is the highest score a scorer gives to a server
+const maxScore = 100
+
+// candidate is a server a database may be placed on, with the stats the scorers work with
+type candidate struct {
+	srv            *server
+	databases      int
+	size           int64
+	connections    int
+	maxConnections int
+}
+
+// scorer rates the candidates for a database from 0 to maxScore, higher is better.
+// All candidates are scored at once, so that scores can be normalized across them.
+type scorer interface {
+	score(db *v1beta1.Database, candidates []*candidate) []int64
+}
+
+// scorers are the scoring strategies which can be enabled in the "placement" config
+var scorers = map[string]scorer{
+	"LeastDatabases":         leastDatabases{},
+	"LeastSize":              leastSize{},
+	"MostConnectionHeadroom": mostConnectionHeadroom{},
+}
+
+type weightedScorer struct {
+	Name   string `mapstructure:"name"`
+	Weight int64  `mapstructure:"weight"`
+}
+
+// placement are the scorers in use with their weights
+var placement = []weightedScorer{
+	{Name: "LeastDatabases", Weight: 1},
+	{Name: "LeastSize", Weight: 1},
+	{Name: "MostConnectionHeadroom", Weight: 1},
+}
+
+// readPlacement reads the scorers from the "placement" list of the operator config
+func readPlacement() {
+	if !viper.IsSet("placement") {
+		return
+	}
+	var configured []weightedScorer
+	if err := viper.UnmarshalKey("placement", &configured); err != nil {
+		log.Error(err, "Failed to read placement from config")
+		os.Exit(1)
+	}
+	for _, s := range configured {
+		if _, ok := scorers[s.Name]; !ok {
+			log.Error(fmt.Errorf("unknown scorer %s", s.Name), "Failed to read placement from config")
+			os.Exit(1)
+		}
+	}
+	placement = configured
+}
+
+// unschedulableError lists why no server fits a database
+type unschedulableError struct {
+	reasons []string
+}
+
+func (e *unschedulableError) Error() string {
+	return fmt.Sprintf("no server fits the database: %s", strings.Join(e.reasons, "; "))
+}
+
+// schedule picks a server for a database which has no spec.serverRef. Servers are filtered by
+// engine, namespace access, spec.serverSelector, capacity and the DatabaseQuotas of the namespace,
+// the rest is ranked by the weighted sum of the placement scorers.
+func schedule(c client.Client, db *v1beta1.Database, ns *corev1.Namespace) (*server, error) {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var candidates []*candidate
+	var reasons []string
+	for _, name := range names {
+		srv := servers[name]
+		reason, err := fits(c, db, ns, srv)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", name, reason))
+			continue
+		}
+
+		cand, err := srv.postgresStats()
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: unreachable", name))
+			continue
+		}
+		if srv.Capacity.Databases > 0 && cand.databases >= srv.Capacity.Databases {
+			reasons = append(reasons, fmt.Sprintf("%s: database capacity reached", name))
+			continue
+		}
+		candidates = append(candidates, cand)
+	}
+	if len(candidates) == 0 {
+		return nil, &unschedulableError{reasons: reasons}
+	}
+
+	totals := make([]int64, len(candidates))
+	for _, p := range placement {
+		for i, s := range scorers[p.Name].score(db, candidates) {
+			totals[i] += p.Weight * s
+		}
+	}
+
+	best := 0
+	for i := range candidates {
+		if totals[i] > totals[best] {
+			best = i
+		}
+	}
+	return candidates[best].srv, nil
+}
+
+// fits returns why the database can't be placed on srv, or an empty string if it can. The
+// capacity is checked once the server is measured.
+func fits(c client.Client, db *v1beta1.Database, ns *corev1.Namespace, srv *server) (string, error) {
+	switch {
+	case srv.Type != db.Spec.Type:
+		return fmt.Sprintf("engine is %s", srv.Type), nil
+	case !srv.allows(ns):
+		return "namespace is not allowed", nil
+	case !labels.SelectorFromSet(db.Spec.ServerSelector).Matches(labels.Set(srv.Labels)):
+		return "labels don't match serverSelector", nil
+	case db.Spec.CloneFrom != nil && db.Spec.CloneFrom.Template != "" && !util.Contains(srv.Templates, db.Spec.CloneFrom.Template):
+		return fmt.Sprintf("template %s is not available", db.Spec.CloneFrom.Template), nil
+	}
+
+	if err := quota.Admit(c, db, nil, srv.Name); err != nil {
+		if quota.IsExceeded(err) {
+			return err.Error(), nil
+		}
+		return "", err
+	}
+	return "", nil
+}
+
+// leastDatabases prefers servers with fewer databases, relative to their capacity if it's set
+type leastDatabases struct{}
+
+func (leastDatabases) score(_ *v1beta1.Database, candidates []*candidate) []int64 {
+	max := 0
+	for _, c := range candidates {
+		if c.databases > max {
+			max = c.databases
+		}
+	}
+
+	scores := make([]int64, len(candidates))
+	for i, c := range candidates {
+		limit := max
+		if c.srv.Capacity.Databases > 0 {
+			limit = c.srv.Capacity.Databases
+		}
+		scores[i] = headroom(int64(c.databases), int64(limit))
+	}
+	return scores
+}
+
+// leastSize prefers servers with less data on them, relative to their capacity if it's set
+type leastSize struct{}
+
+func (leastSize) score(_ *v1beta1.Database, candidates []*candidate) []int64 {
+	var max int64
+	for _, c := range candidates {
+		if c.size > max {
+			max = c.size
+		}
+	}
+
+	scores := make([]int64, len(candidates))
+	for i, c := range candidates {
+		limit := max
+		if c.srv.Capacity.size > 0 {
+			limit = c.srv.Capacity.size
+		}
+		scores[i] = headroom(c.size, limit)
+	}
+	return scores
+}
+
+// mostConnectionHeadroom prefers servers with more free connections
+type mostConnectionHeadroom struct{}
+
+func (mostConnectionHeadroom) score(_ *v1beta1.Database, candidates []*candidate) []int64 {
+	scores := make([]int64, len(candidates))
+	for i, c := range candidates {
+		scores[i] = headroom(int64(c.connections), int64(c.maxConnections))
+	}
+	return scores
+}
+
+// headroom scales the unused part of limit to 0..maxScore
+func headroom(used, limit int64) int64 {
+	if limit <= 0 {
+		return maxScore
+	}
+	if used >= limit {
+		return 0
+	}
+	return maxScore * (limit - used) / limit
+}
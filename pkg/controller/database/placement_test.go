@@ -0,0 +1,104 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"db-operator/pkg/apis"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHeadroom(t *testing.T) {
+	for _, c := range []struct {
+		used, limit, want int64
+	}{
+		{0, 10, 100},
+		{5, 10, 50},
+		{9, 10, 10},
+		{10, 10, 0},
+		{12, 10, 0},
+		{5, 0, 100},
+		{0, 0, 100},
+	} {
+		if got := headroom(c.used, c.limit); got != c.want {
+			t.Errorf("headroom(%d, %d) = %d, want %d", c.used, c.limit, got, c.want)
+		}
+	}
+}
+
+func TestScorers(t *testing.T) {
+	small := &server{Name: "small", Capacity: serverCapacity{Databases: 10, size: 100}}
+	large := &server{Name: "large", Capacity: serverCapacity{Databases: 100, size: 1000}}
+	unlimited := &server{Name: "unlimited"}
+
+	for _, c := range []struct {
+		scorer     string
+		candidates []*candidate
+		want       string
+	}{
+		// Relative to the most used server without capacity
+		{"LeastDatabases", []*candidate{{srv: unlimited, databases: 4}, {srv: unlimited, databases: 1}, {srv: unlimited}}, "[0 75 100]"},
+		{"LeastDatabases", []*candidate{{srv: unlimited}, {srv: unlimited}}, "[100 100]"},
+		// Relative to the capacity where it's set
+		{"LeastDatabases", []*candidate{{srv: small, databases: 5}, {srv: large, databases: 20}, {srv: unlimited, databases: 40}}, "[50 80 0]"},
+		{"LeastDatabases", []*candidate{{srv: small, databases: 12}}, "[0]"},
+		{"LeastSize", []*candidate{{srv: unlimited, size: 200}, {srv: unlimited, size: 50}}, "[0 75]"},
+		{"LeastSize", []*candidate{{srv: small, size: 90}, {srv: large, size: 500}, {srv: unlimited, size: 600}}, "[10 50 0]"},
+		{"MostConnectionHeadroom", []*candidate{{srv: small, connections: 10, maxConnections: 100}, {srv: large, connections: 90, maxConnections: 100}, {srv: unlimited, connections: 5}}, "[90 10 100]"},
+	} {
+		if got := fmt.Sprint(scorers[c.scorer].score(&dbv1beta1.Database{}, c.candidates)); got != c.want {
+			t.Errorf("%s of %d candidates = %s, want %s", c.scorer, len(c.candidates), got, c.want)
+		}
+	}
+}
+
+func TestFits(t *testing.T) {
+	// The fake client decodes lists with the client-go scheme
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+	quota := &dbv1beta1.DatabaseQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "servers", Namespace: "team"},
+		Spec:       dbv1beta1.DatabaseQuotaSpec{AllowedServers: []string{"db1", "db2", "db3"}},
+	}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, quota)
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team"}}
+
+	for _, tc := range []struct {
+		srv  server
+		edit func(db *dbv1beta1.Database)
+		want string
+	}{
+		{server{Name: "db1", Type: "postgres"}, func(db *dbv1beta1.Database) {}, ""},
+		{server{Name: "db1", Type: "mysql"}, func(db *dbv1beta1.Database) {}, "engine is mysql"},
+		{server{Name: "db1", Type: "postgres", AllowedNamespaces: []string{"other"}}, func(db *dbv1beta1.Database) {}, "namespace is not allowed"},
+		{server{Name: "db1", Type: "postgres", Labels: map[string]string{"tier": "dev"}}, func(db *dbv1beta1.Database) {
+			db.Spec.ServerSelector = map[string]string{"tier": "prod"}
+		}, "labels don't match serverSelector"},
+		{server{Name: "db2", Type: "postgres", Labels: map[string]string{"tier": "prod", "zone": "a"}}, func(db *dbv1beta1.Database) {
+			db.Spec.ServerSelector = map[string]string{"tier": "prod"}
+		}, ""},
+		{server{Name: "db1", Type: "postgres", Templates: []string{"base"}}, func(db *dbv1beta1.Database) {
+			db.Spec.CloneFrom = &dbv1beta1.CloneSource{Template: "postgis"}
+		}, "template postgis is not available"},
+		{server{Name: "db3", Type: "postgres", Templates: []string{"base", "postgis"}}, func(db *dbv1beta1.Database) {
+			db.Spec.CloneFrom = &dbv1beta1.CloneSource{Template: "postgis"}
+		}, ""},
+		{server{Name: "db4", Type: "postgres"}, func(db *dbv1beta1.Database) {}, "exceeded DatabaseQuota servers: server db4 is not allowed"},
+	} {
+		db := &dbv1beta1.Database{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"}, Spec: dbv1beta1.DatabaseSpec{Type: "postgres"}}
+		tc.edit(db)
+		got, err := fits(c, db, ns, &tc.srv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("fits(%s) = %q, want %q", tc.srv.Name, got, tc.want)
+		}
+	}
+}
@@ -2,85 +2,137 @@ package database
 
 import (
 	"database/sql"
-	"db-operator/pkg/apis/db/v1alpha1"
+	"db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/util"
+	"errors"
 	"fmt"
-	_ "github.com/lib/pq"
-	"github.com/spf13/viper"
-	"os"
+	"github.com/lib/pq"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"strings"
+	"time"
 )
 
-type DB struct {
-	*sql.DB
-}
-
-var dbCon *sql.DB
-
-// Each database type will have it's own config file
-func init() {
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("/config")
-	viper.SetConfigName("postgres")
-	log.Info("Initializing postgresql config")
-	// TODO: set defaults
-
-	err := viper.ReadInConfig()
-	if err != nil {
-		log.Error(err, "Failed to read config")
-		os.Exit(1)
-	}
-
-	connStr := fmt.Sprintf("dbname=%s user=%s password=%s host=%s sslmode=disable",
-		viper.GetString("dbDatabase"),
-		viper.GetString("dbUser"),
-		viper.GetString("dbPassword"),
-		viper.GetString("dbHost"))
-
-	dbCon, err = sql.Open("postgres", connStr)
-	if err != nil {
-		log.Error(err, "Unable to connect to the database")
-	}
-
-	if err = dbCon.Ping(); err != nil {
-		log.Error(err, "Unable to access database")
-	}
-}
-
-func postgresUpdateEvent(db *v1alpha1.Database, usr *user) error {
-	users := db.Spec.Users
+func (s *server) postgresUpdateEvent(db *v1beta1.Database, usr *user, ev eventer) error {
+	users := db.Spec.UserNames()
 	if db.Status.Phase == "" {
 		//TODO
 		log.Info("Create event")
 		//reqLogger.Info("Creating a new Database", "Db.Namespace", instance.Namespace, "Db.Name", instance.Name)
 		// If we have nothing in the status -> it's create event
-		err := postgresCreateDB(db.Name)
+		var err error
+		// Nobody may connect to a clone before it's copied and masked
+		blocked := db.Status.Clone != nil
+		if db.Status.UndeletedFrom != "" {
+			err = s.postgresUntrash(db.Status.UndeletedFrom, db.Name)
+		} else {
+			err = s.postgresCreateDB(db.Name, cloneTemplate(db), db.Spec.Settings, blocked)
+		}
+		if err == errTemplateInUse && blocked {
+			// The source is copied by a Job into an empty database instead
+			db.Status.Clone.Method = v1beta1.CloneDump
+			ev.normal(reasonCloning, "Template %s is in use, copying it with pg_dump", db.Status.Clone.Source)
+			err = s.postgresCreateDB(db.Name, "", db.Spec.Settings, true)
+		}
 		if err != nil {
 			return err
 		}
-		err = postgresCreateUser(db, usr)
+		if db.Status.UndeletedFrom == "" {
+			db.Status.Template = createdFrom(db)
+		}
+		databasesCreated.WithLabelValues(s.Type, s.Name).Inc()
+		ev.normal(reasonCreated, "Database %s created on server %s", db.Name, s.Name)
+		err = s.postgresCreateUser(db, usr)
 		if err != nil {
 			return err
 		}
+		ev.normal(reasonUserCreated, "User %s created", usr.username)
 		users = append(users, usr.username)
-		err = postgresGrantAllWithRole(users, db.Name)
+		err = s.postgresGrantAllWithRole(users, db.Name)
 		if err != nil {
 			return err
 		}
+		ev.normal(reasonGranted, "Granted %s_owners to %s", db.Name, strings.Join(users, ", "))
+
+		// The clone is opened with CONNECT revoked, clone gives access back once it's done
+		if blocked {
+			if err := s.postgresRevokeAccess(db.Name); err != nil {
+				return err
+			}
+			if err := s.postgresAllowConnections(db.Name); err != nil {
+				return err
+			}
+		}
+
+		// The objects of an undeleted database were handed to the admin, and access was revoked
+		if db.Status.UndeletedFrom != "" {
+			if err := s.postgresOwnObjects(db.Name); err != nil {
+				return err
+			}
+			if err := s.postgresRestoreAccess(db.Name); err != nil {
+				return err
+			}
+			ev.normal(reasonUndeleted, "Database %s restored from %s", db.Name, db.Status.UndeletedFrom)
+		}
 
 		db.Status.Phase = "Created"
-		return err
+		db.Status.Server = s.Name
+		return s.postgresAlter(db, ev)
 
 	} else {
 		users = append(users, db.Name)
-		err := postgresUpdateGrants(users, db.Name)
+		err := s.postgresUpdateGrants(users, db.Name, ev)
+		if err != nil {
+			return err
+		}
 
-		return err
+		return s.postgresAlter(db, ev)
 	}
 }
 
-func postgresCreateDB(dbName string) error {
+// errTemplateInUse is returned by postgresCreateDB if others are connected to the template
+var errTemplateInUse = errors.New("template database is in use")
+
+// postgresCreateDB creates an empty database, or a copy of template if it's set. The owner
+// in settings is applied by postgresApplySettings, its role may not exist yet. Blocked databases
+// allow no connections until postgresAllowConnections, so that CONNECT can be revoked first.
+func (s *server) postgresCreateDB(dbName string, template string, settings *v1beta1.DatabaseSettings, blocked bool) error {
 	query := fmt.Sprintf(`CREATE DATABASE "%s"`, dbName)
-	_, err := dbCon.Exec(query)
+	if blocked {
+		query += ` ALLOW_CONNECTIONS false`
+	}
+	if settings != nil {
+		if template == "" {
+			template = settings.Template
+		}
+		if settings.Encoding != "" {
+			query += fmt.Sprintf(` ENCODING %s`, pq.QuoteLiteral(settings.Encoding))
+		}
+		if settings.LCCollate != "" {
+			query += fmt.Sprintf(` LC_COLLATE %s`, pq.QuoteLiteral(settings.LCCollate))
+		}
+		if settings.LCCtype != "" {
+			query += fmt.Sprintf(` LC_CTYPE %s`, pq.QuoteLiteral(settings.LCCtype))
+		}
+		if settings.ICULocale != "" {
+			query += fmt.Sprintf(` LOCALE_PROVIDER icu ICU_LOCALE %s`, pq.QuoteLiteral(settings.ICULocale))
+		}
+		if settings.ConnectionLimit != nil {
+			query += fmt.Sprintf(` CONNECTION LIMIT %d`, *settings.ConnectionLimit)
+		}
+		if settings.Tablespace != "" {
+			query += fmt.Sprintf(` TABLESPACE "%s"`, settings.Tablespace)
+		}
+	}
+	if template != "" {
+		query += fmt.Sprintf(` TEMPLATE "%s"`, template)
+	}
+	_, err := s.exec(opCreateDB, query)
+	if e, ok := err.(*pq.Error); ok && template != "" && e.Code == "55006" {
+		log.Info("Template database is in use", "Database:", dbName, "Template:", template)
+		return errTemplateInUse
+	}
 	if err != nil {
 		log.Error(err, "Unable to create database", "Database:", dbName)
 		return err
@@ -90,16 +142,33 @@ func postgresCreateDB(dbName string) error {
 	return err
 }
 
-func postgresCreateUser(db *v1alpha1.Database, usr *user) error {
-	password, err := genPassword()
-	if err != nil {
-		return err
+// postgresAllowConnections opens a database created blocked by postgresCreateDB
+func (s *server) postgresAllowConnections(database string) error {
+	query := fmt.Sprintf(`ALTER DATABASE "%s" ALLOW_CONNECTIONS true`, database)
+	_, err := s.exec(opGrant, query)
+	return err
+}
+
+// postgresSetPassword gives the user a new password
+func (s *server) postgresSetPassword(usr *user) error {
+	query := fmt.Sprintf(`ALTER USER "%s" WITH ENCRYPTED PASSWORD '%s'`, usr.username, usr.password)
+	_, err := s.exec(opCreateUser, query)
+	return err
+}
+
+// postgresCreateUser creates the user of the database, with a new password unless usr already has one
+func (s *server) postgresCreateUser(db *v1beta1.Database, usr *user) error {
+	if usr.password == "" {
+		password, err := genPassword()
+		if err != nil {
+			return err
+		}
+		usr.password = password
 	}
-	usr.password = password
 	usr.username = db.Name
 
 	query := fmt.Sprintf(`CREATE USER "%s" WITH ENCRYPTED PASSWORD '%s'`, usr.username, usr.password)
-	_, err = dbCon.Exec(query)
+	_, err := s.exec(opCreateUser, query)
 	if err != nil {
 		log.Error(err, "Unable to create user", "User:", usr.username)
 		return err
@@ -110,9 +179,9 @@ func postgresCreateUser(db *v1alpha1.Database, usr *user) error {
 	return err
 }
 
-func postgresRevokeUser(user string, role string) error {
+func (s *server) postgresRevokeUser(user string, role string) error {
 	query := fmt.Sprintf(`REVOKE "%s" FROM "%s"`, role, user)
-	_, err := dbCon.Exec(query)
+	_, err := s.exec(opRevoke, query)
 	return err
 }
 
@@ -120,9 +189,9 @@ func postgresDelUser(userName string) (string, error) {
 	return "nil", nil
 }
 
-func postgresDelDB(dbName string) error {
+func (s *server) postgresDelDB(dbName string) error {
 	query := fmt.Sprintf(`DROP DATABASE "%s"`, dbName)
-	_, err := dbCon.Exec(query)
+	_, err := s.exec(opDrop, query)
 	if err != nil {
 		log.Error(err, "Unable to drop the database", "Database:", dbName)
 	}
@@ -130,29 +199,29 @@ func postgresDelDB(dbName string) error {
 	return err
 }
 
-func postgresGrantAllWithRole(users []string, database string) error {
+func (s *server) postgresGrantAllWithRole(users []string, database string) error {
 	roleName := fmt.Sprintf(`%s_owners`, database)
 	query := fmt.Sprintf(`CREATE ROLE "%s"`, roleName)
-	_, err := dbCon.Exec(query)
+	_, err := s.exec(opGrant, query)
 	if err != nil {
 		log.Error(err, "Unable to create ROLE", "Role prefix:", database)
 		return err
 	}
 
 	query = fmt.Sprintf(`GRANT ALL on DATABASE "%s" to "%s"`, database, roleName)
-	if _, err := dbCon.Exec(query); err != nil {
+	if _, err := s.exec(opGrant, query); err != nil {
 		return err
 	}
 
-	err = postgresGrantAll(users, roleName)
+	err = s.postgresGrantAll(users, roleName)
 
 	return err
 }
 
-func postgresGrantAll(users []string, database string) error {
+func (s *server) postgresGrantAll(users []string, database string) error {
 	userlist := strings.Join(users, `", "`)
 	query := fmt.Sprintf(`GRANT "%s" to "%s"`, database, userlist)
-	_, err := dbCon.Exec(query)
+	_, err := s.exec(opGrant, query)
 	if err != nil {
 		log.Error(err, "Unable to assign permissions", "Database:", database, "Users: ", userlist)
 	}
@@ -160,35 +229,37 @@ func postgresGrantAll(users []string, database string) error {
 	return err
 }
 
-func postgresUpdateGrants(users []string, database string) error {
+func (s *server) postgresUpdateGrants(users []string, database string, ev eventer) error {
 	roleName := fmt.Sprintf(`%s_owners`, database)
-	currentUsers, err := getRoleUsers(roleName)
+	currentUsers, err := s.getRoleUsers(roleName)
 	if err != nil {
 		return err
 	}
 	// Revoke access if users were removed from the object
 	for _, u := range currentUsers {
-		if !contains(users, u) {
-			err = postgresRevokeUser(u, roleName)
+		if !util.Contains(users, u) {
+			err = s.postgresRevokeUser(u, roleName)
 			if err != nil {
 				return err
 			}
+			ev.normal(reasonRevoked, "Revoked %s from %s", roleName, u)
 		}
 	}
 	// Grant access for newly created users
 	for _, u := range users {
-		if !contains(currentUsers, u) {
-			err = postgresGrantAll([]string{u}, roleName)
+		if !util.Contains(currentUsers, u) {
+			err = s.postgresGrantAll([]string{u}, roleName)
 			if err != nil {
 				return err
 			}
+			ev.normal(reasonGranted, "Granted %s to %s", roleName, u)
 		}
 	}
 
 	return err
 }
 
-func getRoleUsers(roleName string) ([]string, error) {
+func (s *server) getRoleUsers(roleName string) ([]string, error) {
 	queryString := `select usename
 		from pg_user
 		join pg_auth_members on (pg_user.usesysid = pg_auth_members.member)
@@ -196,7 +267,7 @@ func getRoleUsers(roleName string) ([]string, error) {
 
 	query := fmt.Sprintf(queryString, roleName)
 
-	rows, err := dbCon.Query(query)
+	rows, err := s.con.Query(query)
 	if err != nil {
 		log.Error(err, err.Error())
 	}
@@ -216,19 +287,18 @@ func getRoleUsers(roleName string) ([]string, error) {
 	return users, err
 }
 
-func postgresDeleteEvent(db *v1alpha1.Database) error {
-	if db.Spec.Drop {
-		err := postgresDelDB(db.Name)
-		if err != nil {
-			return err
-		}
-	} else {
-		log.Info("Database won't be deleted! Protected is set to true.")
+// postgresDeleteEvent drops the database with its _owners role and its user
+func (s *server) postgresDeleteEvent(db *v1beta1.Database, ev eventer) error {
+	err := s.postgresDelDB(db.Name)
+	if err != nil {
+		return err
 	}
+	databasesDropped.WithLabelValues(s.Type, s.Name).Inc()
+	ev.normal(reasonDropped, "Database %s dropped from server %s", db.Name, s.Name)
 
 	roleName := fmt.Sprintf(`%s_owners`, db.Name)
 	query := fmt.Sprintf(`DROP ROLE "%s"`, roleName)
-	_, err := dbCon.Exec(query)
+	_, err = s.exec(opDrop, query)
 	if err != nil {
 		log.Error(err, "Unable to drop ROLE", "Role:", roleName)
 		return err
@@ -236,7 +306,7 @@ func postgresDeleteEvent(db *v1alpha1.Database) error {
 		log.Info("Role was successfully deleted", "Role:", roleName)
 	}
 	query = fmt.Sprintf(`DROP USER "%s"`, db.Name)
-	_, err = dbCon.Exec(query)
+	_, err = s.exec(opDrop, query)
 	if err != nil {
 		log.Error(err, "Unable to drop User", "User:", db.Name)
 		return err
@@ -246,3 +316,447 @@ func postgresDeleteEvent(db *v1alpha1.Database) error {
 
 	return err
 }
+
+// postgresAlter brings the settings, parameters, schemas and extensions of the database and its roles in line with the spec
+func (s *server) postgresAlter(db *v1beta1.Database, ev eventer) error {
+	if err := s.postgresApplySettings(db, ev); err != nil {
+		return err
+	}
+	if err := s.postgresApplyParameters(db, ev); err != nil {
+		return err
+	}
+	if err := s.postgresUpdateRoles(db, ev); err != nil {
+		return err
+	}
+	if err := s.postgresUpdateSchemas(db, ev); err != nil {
+		return err
+	}
+	return s.postgresUpdateExtensions(db, ev)
+}
+
+// postgresApplySettings alters the mutable settings of the database which differ from
+// spec.settings and reports immutable ones which differ in the SettingsApplied condition
+func (s *server) postgresApplySettings(db *v1beta1.Database, ev eventer) error {
+	settings := db.Spec.Settings
+	if settings == nil {
+		return nil
+	}
+
+	var encoding, collate, ctype, icuLocale, tablespace, owner string
+	var connectionLimit int32
+	// The ICU locale is in daticulocale since postgres 15 and in datlocale since 17
+	query := `SELECT pg_encoding_to_char(d.encoding), d.datcollate, d.datctype,
+			COALESCE(to_jsonb(d)->>'datlocale', to_jsonb(d)->>'daticulocale', ''),
+			d.datconnlimit, t.spcname, pg_get_userbyid(d.datdba)
+		FROM pg_database d JOIN pg_tablespace t ON t.oid = d.dattablespace WHERE d.datname = $1`
+	err := s.con.QueryRow(query, db.Name).Scan(&encoding, &collate, &ctype, &icuLocale, &connectionLimit, &tablespace, &owner)
+	if err != nil {
+		log.Error(err, "Unable to read database settings", "Database:", db.Name)
+		return err
+	}
+
+	var conflicts []string
+	if settings.Encoding != "" && encodingName(settings.Encoding) != encodingName(encoding) {
+		conflicts = append(conflicts, fmt.Sprintf("encoding is %s", encoding))
+	}
+	if settings.LCCollate != "" && settings.LCCollate != collate {
+		conflicts = append(conflicts, fmt.Sprintf("lcCollate is %s", collate))
+	}
+	if settings.LCCtype != "" && settings.LCCtype != ctype {
+		conflicts = append(conflicts, fmt.Sprintf("lcCtype is %s", ctype))
+	}
+	if settings.ICULocale != "" && settings.ICULocale != icuLocale {
+		conflicts = append(conflicts, fmt.Sprintf("icuLocale is %s", valueOrNone(icuLocale)))
+	}
+	// The server doesn't remember the template, databases created before it was recorded adopt it
+	if db.Status.Template == "" && db.Status.UndeletedFrom == "" {
+		db.Status.Template = createdFrom(db)
+	}
+	if settings.Template != "" && db.Status.Template != "" && settings.Template != db.Status.Template {
+		conflicts = append(conflicts, fmt.Sprintf("template is %s", db.Status.Template))
+	}
+	refusal := db.OwnerRefusal()
+	switch {
+	case len(conflicts) > 0:
+		msg := fmt.Sprintf("Settings can't be changed after the database is created: %s", strings.Join(conflicts, ", "))
+		if c := db.Status.GetCondition(v1beta1.DatabaseSettingsApplied); c == nil || c.Message != msg {
+			ev.warning(reasonAltered, "%s", msg)
+		}
+		db.Status.SetCondition(v1beta1.DatabaseSettingsApplied, corev1.ConditionFalse, "ImmutableSettingChanged", msg)
+	case refusal != "":
+		// The webhook refuses other owners, the database is never handed to roles it doesn't own
+		if c := db.Status.GetCondition(v1beta1.DatabaseSettingsApplied); c == nil || c.Message != refusal {
+			ev.warning(reasonAltered, "%s", refusal)
+		}
+		db.Status.SetCondition(v1beta1.DatabaseSettingsApplied, corev1.ConditionFalse, "OwnerNotAllowed", refusal)
+	default:
+		db.Status.SetCondition(v1beta1.DatabaseSettingsApplied, corev1.ConditionTrue, "Applied", "")
+	}
+
+	if settings.ConnectionLimit != nil && *settings.ConnectionLimit != connectionLimit {
+		query := fmt.Sprintf(`ALTER DATABASE "%s" CONNECTION LIMIT %d`, db.Name, *settings.ConnectionLimit)
+		if _, err := s.exec(opAlter, query); err != nil {
+			return err
+		}
+		ev.normal(reasonAltered, "Connection limit of database %s set to %d", db.Name, *settings.ConnectionLimit)
+	}
+	if settings.Owner != "" && settings.Owner != owner && refusal == "" {
+		// Handing the database over requires membership in the new owner, one of the database's roles
+		if _, err := s.exec(opGrant, fmt.Sprintf(`GRANT "%s" TO CURRENT_USER`, settings.Owner)); err != nil {
+			return err
+		}
+		query := fmt.Sprintf(`ALTER DATABASE "%s" OWNER TO "%s"`, db.Name, settings.Owner)
+		if _, err := s.exec(opAlter, query); err != nil {
+			return err
+		}
+		ev.normal(reasonAltered, "Owner of database %s set to %s", db.Name, settings.Owner)
+
+		// The new owner takes over the privileges of the old one, while access is revoked
+		// they're taken away again
+		var revoked bool
+		query = `SELECT NOT EXISTS (SELECT 1 FROM pg_database d, aclexplode(COALESCE(d.datacl, acldefault('d', d.datdba))) a
+			WHERE d.datname = $1 AND a.grantee = 0 AND a.privilege_type = 'CONNECT')`
+		if err := s.con.QueryRow(query, db.Name).Scan(&revoked); err != nil {
+			return err
+		}
+		if revoked {
+			if err := s.postgresRevokeAccess(db.Name); err != nil {
+				return err
+			}
+		}
+	}
+	if settings.Tablespace != "" && settings.Tablespace != tablespace {
+		query := fmt.Sprintf(`ALTER DATABASE "%s" SET TABLESPACE "%s"`, db.Name, settings.Tablespace)
+		if _, err := s.exec(opAlter, query); err != nil {
+			log.Error(err, "Unable to move database, it must not be in use", "Database:", db.Name, "Tablespace:", settings.Tablespace)
+			return err
+		}
+		ev.normal(reasonAltered, "Database %s moved to tablespace %s", db.Name, settings.Tablespace)
+	}
+	return nil
+}
+
+// createdFrom is the template the database is created from
+func createdFrom(db *v1beta1.Database) string {
+	if template := cloneTemplate(db); template != "" {
+		return template
+	}
+	if db.Spec.Settings != nil && db.Spec.Settings.Template != "" {
+		return db.Spec.Settings.Template
+	}
+	return "template1"
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// encodingName normalizes encoding names the way postgres compares them, e.g. utf-8 is UTF8
+func encodingName(encoding string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", "_", "").Replace(encoding))
+}
+
+// postgresExists reports if the database exists on the server
+func (s *server) postgresExists(database string) (bool, error) {
+	var exists bool
+	err := s.con.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)`, database).Scan(&exists)
+	return exists, err
+}
+
+// postgresRoleExists reports if the role exists on the server
+func (s *server) postgresRoleExists(role string) (bool, error) {
+	var exists bool
+	err := s.con.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)`, role).Scan(&exists)
+	return exists, err
+}
+
+// postgresTrash renames the database to trash and takes all access away. The objects of the
+// database roles are handed to the admin, so that the roles can be dropped.
+func (s *server) postgresTrash(database, trash string) error {
+	exists, err := s.postgresExists(database)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := s.postgresRevokeAccess(database); err != nil {
+			return err
+		}
+		query := fmt.Sprintf(`ALTER DATABASE "%s" RENAME TO "%s"`, database, trash)
+		if _, err := s.exec(opDrop, query); err != nil {
+			log.Error(err, "Unable to rename the database", "Database:", database, "Trash:", trash)
+			return err
+		}
+	}
+
+	con, err := s.connectTo(trash)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+
+	for _, role := range []string{database + "_owners", database} {
+		var exists bool
+		if err := s.con.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)`, role).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		// Reassigning requires membership in the role
+		if _, err := s.exec(opGrant, fmt.Sprintf(`GRANT "%s" TO CURRENT_USER`, role)); err != nil {
+			return err
+		}
+		query := fmt.Sprintf(`REASSIGN OWNED BY "%[1]s" TO CURRENT_USER; DROP OWNED BY "%[1]s"`, role)
+		if _, err := con.Exec(query); err != nil {
+			log.Error(err, "Unable to reassign objects", "Database:", trash, "Role:", role)
+			return err
+		}
+	}
+	return nil
+}
+
+// postgresUntrash renames the soft deleted database trash back to database
+func (s *server) postgresUntrash(trash, database string) error {
+	exists, err := s.postgresExists(trash)
+	if err != nil || !exists {
+		// Renamed already by an earlier attempt
+		return err
+	}
+	query := fmt.Sprintf(`ALTER DATABASE "%s" RENAME TO "%s"`, trash, database)
+	_, err = s.exec(opCreateDB, query)
+	if err != nil {
+		log.Error(err, "Unable to rename the database", "Trash:", trash, "Database:", database)
+	}
+	return err
+}
+
+// postgresUsage measures every database on the server in a single query
+func (s *server) postgresUsage() (map[string]v1beta1.DatabaseUsage, error) {
+	query := `SELECT d.datname, pg_database_size(d.datname), age(d.datfrozenxid),
+			(SELECT count(*) FROM pg_stat_activity a WHERE a.datname = d.datname)
+		FROM pg_database d
+		WHERE NOT d.datistemplate AND d.datallowconn`
+
+	start := time.Now()
+	rows, err := s.con.Query(query)
+	sqlDuration.WithLabelValues(s.Type, s.Name, opUsage).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := metav1.Now()
+	usage := map[string]v1beta1.DatabaseUsage{}
+	for rows.Next() {
+		var name string
+		var size, age int64
+		var connections int32
+		if err := rows.Scan(&name, &size, &age, &connections); err != nil {
+			return nil, err
+		}
+		usage[name] = v1beta1.DatabaseUsage{
+			Size:             *resource.NewQuantity(size, resource.BinarySI),
+			Connections:      connections,
+			TransactionIDAge: age,
+			LastMeasured:     now,
+		}
+	}
+
+	return usage, rows.Err()
+}
+
+// postgresRevokeAccess takes CONNECT and CREATE away from the database roles, and from the
+// owner of the database which has them implicitly, and terminates the open sessions, so nothing
+// can be written until access is restored
+func (s *server) postgresRevokeAccess(database string) error {
+	roles, err := s.accessRoles(database)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`REVOKE CONNECT, CREATE ON DATABASE "%s" FROM PUBLIC, "%s"`, database, strings.Join(roles, `", "`))
+	if _, err := s.exec(opRevoke, query); err != nil {
+		log.Error(err, "Unable to revoke access", "Database:", database)
+		return err
+	}
+
+	query = fmt.Sprintf(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE datname = '%s' AND pid <> pg_backend_pid()`, database)
+	_, err = s.exec(opRevoke, query)
+	return err
+}
+
+// postgresRestoreAccess gives back the privileges taken by postgresRevokeAccess
+func (s *server) postgresRestoreAccess(database string) error {
+	roles, err := s.accessRoles(database)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`GRANT ALL on DATABASE "%s" to "%s"`, database, strings.Join(roles, `", "`))
+	if _, err := s.exec(opGrant, query); err != nil {
+		log.Error(err, "Unable to restore access", "Database:", database)
+		return err
+	}
+
+	query = fmt.Sprintf(`GRANT CONNECT ON DATABASE "%s" TO PUBLIC`, database)
+	_, err = s.exec(opGrant, query)
+	return err
+}
+
+// accessRoles are the _owners role of the database and its owner, unless that's the admin
+func (s *server) accessRoles(database string) ([]string, error) {
+	roles := []string{database + "_owners"}
+	var owner string
+	err := s.con.QueryRow(`SELECT pg_get_userbyid(datdba) FROM pg_database
+		WHERE datname = $1 AND datdba <> (SELECT oid FROM pg_roles WHERE rolname = current_user)`, database).Scan(&owner)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		log.Error(err, "Unable to read the owner", "Database:", database)
+		return nil, err
+	case owner != roles[0]:
+		roles = append(roles, owner)
+	}
+	return roles, nil
+}
+
+// postgresStats measures the server for placing databases on it
+func (s *server) postgresStats() (*candidate, error) {
+	// Databases are counted on the server, the operator may not watch all namespaces using it
+	query := `SELECT (SELECT coalesce(sum(pg_database_size(datname)), 0) FROM pg_database WHERE datallowconn),
+			(SELECT count(*) FROM pg_database WHERE NOT datistemplate AND datname <> 'postgres'),
+			(SELECT count(*) FROM pg_stat_activity),
+			current_setting('max_connections')::int`
+
+	c := &candidate{srv: s}
+	err := s.con.QueryRow(query).Scan(&c.size, &c.databases, &c.connections, &c.maxConnections)
+	if err != nil {
+		log.Error(err, "Unable to measure server", "Server", s.Name)
+		return nil, err
+	}
+	return c, nil
+}
+
+// ownObjectsQuery hands everything the admin user and the roles in the array %[2]s own in the
+// current database over to the role in %[1]s, skipping system schemas and objects which belong
+// to extensions
+const ownObjectsQuery = `DO $$
+DECLARE
+	r record;
+BEGIN
+	FOR r IN SELECT n.nspname FROM pg_namespace n
+		WHERE n.nspowner = ANY (SELECT oid FROM pg_roles WHERE rolname = current_user OR rolname = ANY (%[2]s)) AND n.nspname NOT LIKE 'pg\_%%' AND n.nspname <> 'information_schema'
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = n.oid AND d.deptype = 'e')
+	LOOP
+		EXECUTE format('ALTER SCHEMA %%I OWNER TO %%I', r.nspname, '%[1]s');
+	END LOOP;
+
+	FOR r IN SELECT c.oid::regclass AS name, c.relkind FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relowner = ANY (SELECT oid FROM pg_roles WHERE rolname = current_user OR rolname = ANY (%[2]s)) AND c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f')
+			AND n.nspname NOT LIKE 'pg\_%%' AND n.nspname <> 'information_schema'
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = c.oid AND d.deptype = 'e')
+	LOOP
+		EXECUTE format('ALTER %%s %%s OWNER TO %%I',
+			CASE r.relkind WHEN 'v' THEN 'VIEW' WHEN 'm' THEN 'MATERIALIZED VIEW' WHEN 'S' THEN 'SEQUENCE'
+				WHEN 'f' THEN 'FOREIGN TABLE' ELSE 'TABLE' END,
+			r.name, '%[1]s');
+	END LOOP;
+
+	FOR r IN SELECT p.oid::regprocedure AS name FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE p.proowner = ANY (SELECT oid FROM pg_roles WHERE rolname = current_user OR rolname = ANY (%[2]s)) AND n.nspname NOT LIKE 'pg\_%%' AND n.nspname <> 'information_schema'
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')
+	LOOP
+		EXECUTE format('ALTER ROUTINE %%s OWNER TO %%I', r.name, '%[1]s');
+	END LOOP;
+
+	FOR r IN SELECT t.oid::regtype AS name, t.typtype FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.typowner = ANY (SELECT oid FROM pg_roles WHERE rolname = current_user OR rolname = ANY (%[2]s)) AND t.typtype IN ('d', 'e', 'r')
+			AND n.nspname NOT LIKE 'pg\_%%' AND n.nspname <> 'information_schema'
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = t.oid AND d.deptype = 'e')
+	LOOP
+		EXECUTE format('ALTER %%s %%s OWNER TO %%I', CASE r.typtype WHEN 'd' THEN 'DOMAIN' ELSE 'TYPE' END, r.name, '%[1]s');
+	END LOOP;
+END
+$$`
+
+// revokeObjectsQuery takes the privileges of the roles in the array %[1]s on the objects
+// in the current database away
+const revokeObjectsQuery = `DO $$
+DECLARE
+	r record;
+BEGIN
+	FOR r IN SELECT n.nspname, g.rolname FROM pg_namespace n, pg_roles g
+		WHERE g.rolname = ANY (%[1]s) AND n.nspname NOT LIKE 'pg\_%%' AND n.nspname <> 'information_schema'
+	LOOP
+		EXECUTE format('REVOKE ALL ON ALL TABLES IN SCHEMA %%I FROM %%I', r.nspname, r.rolname);
+		EXECUTE format('REVOKE ALL ON ALL SEQUENCES IN SCHEMA %%I FROM %%I', r.nspname, r.rolname);
+		EXECUTE format('REVOKE ALL ON ALL FUNCTIONS IN SCHEMA %%I FROM %%I', r.nspname, r.rolname);
+		EXECUTE format('REVOKE ALL ON SCHEMA %%I FROM %%I', r.nspname, r.rolname);
+	END LOOP;
+END
+$$`
+
+// postgresOwnObjects makes the _owners role the owner of the objects in the database which
+// the operator created, e.g. by pg_restore, so that the database users can access them.
+// Objects of the roles in from, e.g. the ones of a cloned database, are handed over as
+// well and the roles lose their privileges.
+func (s *server) postgresOwnObjects(database string, from ...string) error {
+	roleName := fmt.Sprintf(`%s_owners`, database)
+
+	// Handing objects over requires membership in the old and the new owner
+	for _, role := range append([]string{roleName}, from...) {
+		query := fmt.Sprintf(`GRANT "%s" TO CURRENT_USER`, role)
+		if _, err := s.exec(opGrant, query); err != nil {
+			return err
+		}
+	}
+	roles := make([]string, 0, len(from))
+	for _, role := range from {
+		roles = append(roles, pq.QuoteLiteral(role))
+	}
+	array := fmt.Sprintf("ARRAY[%s]::name[]", strings.Join(roles, ", "))
+
+	con, err := s.connectTo(database)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+
+	start := time.Now()
+	_, err = con.Exec(fmt.Sprintf(ownObjectsQuery, roleName, array))
+	if err == nil && len(from) > 0 {
+		_, err = con.Exec(fmt.Sprintf(revokeObjectsQuery, array))
+	}
+	sqlDuration.WithLabelValues(s.Type, s.Name, opGrant).Observe(time.Since(start).Seconds())
+	if err != nil {
+		log.Error(err, "Unable to reassign objects", "Database:", database)
+	}
+	return err
+}
+
+// postgresDropCopy drops a copy of the database left over by a migration, with its user and role.
+// Whatever a failed migration didn't get to create is skipped.
+func (s *server) postgresDropCopy(db *v1beta1.Database, ev eventer) error {
+	exists, err := s.postgresExists(db.Name)
+	if err != nil {
+		return err
+	}
+	queries := []string{
+		fmt.Sprintf(`DROP DATABASE IF EXISTS "%s"`, db.Name),
+		fmt.Sprintf(`DROP ROLE IF EXISTS "%s_owners"`, db.Name),
+		fmt.Sprintf(`DROP USER IF EXISTS "%s"`, db.Name),
+	}
+	for _, query := range queries {
+		if _, err := s.exec(opDrop, query); err != nil {
+			log.Error(err, "Unable to drop copy of the database", "Database:", db.Name, "Server", s.Name)
+			return err
+		}
+	}
+	if exists {
+		databasesDropped.WithLabelValues(s.Type, s.Name).Inc()
+		ev.normal(reasonDropped, "Database %s dropped from server %s", db.Name, s.Name)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"testing"
+
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+)
+
+func TestCreatedFrom(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		edit func(db *dbv1beta1.Database)
+		want string
+	}{
+		{"default", func(db *dbv1beta1.Database) {}, "template1"},
+		{"settings", func(db *dbv1beta1.Database) {
+			db.Spec.Settings = &dbv1beta1.DatabaseSettings{Template: "template0"}
+		}, "template0"},
+		{"settings without template", func(db *dbv1beta1.Database) {
+			db.Spec.Settings = &dbv1beta1.DatabaseSettings{Encoding: "UTF8"}
+		}, "template1"},
+		{"cloned from a template", func(db *dbv1beta1.Database) {
+			db.Spec.Settings = &dbv1beta1.DatabaseSettings{Template: "template0"}
+			db.Status.Clone = &dbv1beta1.DatabaseCloneStatus{Database: "base", Method: dbv1beta1.CloneTemplate, Phase: dbv1beta1.RestoreRunning}
+		}, "base"},
+		{"cloned by dump", func(db *dbv1beta1.Database) {
+			db.Status.Clone = &dbv1beta1.DatabaseCloneStatus{Database: "base", Method: dbv1beta1.CloneDump, Phase: dbv1beta1.RestoreRunning}
+		}, "template1"},
+	} {
+		db := &dbv1beta1.Database{}
+		c.edit(db)
+		if got := createdFrom(db); got != c.want {
+			t.Errorf("%s: createdFrom = %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestEncodingName(t *testing.T) {
+	for encoding, want := range map[string]string{
+		"UTF8":     "UTF8",
+		"utf-8":    "UTF8",
+		"latin1":   "LATIN1",
+		"EUC_JP":   "EUCJP",
+		"win-1252": "WIN1252",
+	} {
+		if got := encodingName(encoding); got != want {
+			t.Errorf("encodingName(%s) = %s, want %s", encoding, got, want)
+		}
+	}
+}
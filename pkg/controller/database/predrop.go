@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/backup"
+	"db-operator/pkg/jobs"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// preDropBackupLabel marks the ConfigMaps recording dumps taken before databases were dropped
+const preDropBackupLabel = "db.clarizen.cloud/pre-drop-backup"
+
+// backupBeforeDrop dumps a database which is about to be dropped to the backup storage.
+// The dump is recorded in a ConfigMap in the operator namespace, which outlives the Database.
+// done is false while the dump runs, the finalizer has to wait for it.
+func (r *ReconcileDatabase) backupBeforeDrop(reqLogger logr.Logger, db *dbv1beta1.Database, ev eventer) (done bool, err error) {
+	if db.Spec.PreDropBackup == nil || db.Spec.DeletionPolicy != dbv1beta1.DeletionPolicyDelete || db.Status.Phase != "Created" {
+		return true, nil
+	}
+
+	deleted := time.Now()
+	if db.GetDeletionTimestamp() != nil {
+		deleted = db.GetDeletionTimestamp().Time
+	}
+	name := jobs.Name("pre-drop", db)
+	recordName := fmt.Sprintf("%s-%d", name, deleted.Unix())
+
+	// The record exists once the dump succeeded, retrying the finalizer doesn't dump again
+	record := &corev1.ConfigMap{}
+	err = r.jobs.Client.Get(context.TODO(), types.NamespacedName{Namespace: r.jobs.Namespace, Name: recordName}, record)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	srv, err := serverFor(db)
+	if err != nil {
+		return false, err
+	}
+	storage, err := backup.StorageFor(db.Spec.PreDropBackup.Storage)
+	if err != nil {
+		return false, err
+	}
+	key := path.Join(db.Namespace, db.Name, fmt.Sprintf("pre-drop-%d.dump", deleted.Unix()))
+
+	state, err := r.jobs.Run(name, db, storage.DumpJob(srv.dsn(db.Name), key))
+	if err != nil {
+		return false, err
+	}
+	switch state {
+	case jobs.Running:
+		reqLogger.Info("Waiting for the backup before dropping the Database")
+		return false, nil
+	case jobs.Failed:
+		// The Job is kept for its logs, the database stays until the dump succeeds
+		// or the deletion policy is changed
+		return false, fmt.Errorf("backup before dropping failed, see Job %s/%s", r.jobs.Namespace, name)
+	}
+
+	size, err := r.jobs.Result(name)
+	if err != nil {
+		return false, err
+	}
+	location := storage.Location(key)
+	record = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      recordName,
+			Namespace: r.jobs.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "db-operator",
+				preDropBackupLabel:             "true",
+				"db.clarizen.cloud/namespace":  db.Namespace,
+				"db.clarizen.cloud/name":       db.Name,
+			},
+		},
+		Data: map[string]string{
+			"namespace": db.Namespace,
+			"name":      db.Name,
+			"server":    srv.Name,
+			"storage":   storage.Name,
+			"location":  location,
+			"size":      size,
+			"createdAt": time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	if err := r.jobs.Client.Create(context.TODO(), record); err != nil && !errors.IsAlreadyExists(err) {
+		return false, err
+	}
+	ev.normal(reasonBackedUp, "Database %s backed up to %s before dropping it, recorded in ConfigMap %s/%s",
+		db.Name, location, record.Namespace, record.Name)
+
+	return true, r.jobs.Cleanup(name)
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// enforceQuota compares the measured usage of db with its size quota. Above the soft limit
+// a QuotaWarning condition is set, above the hard limit access to the database is revoked
+// until usage drops below it again or the quota is raised.
+func enforceQuota(srv *server, db *dbv1beta1.Database, usage dbv1beta1.DatabaseUsage, ev eventer) error {
+	quota := db.Spec.Quota
+	size := usage.Size.Value()
+
+	soft := quota != nil && size > quota.SoftLimit()
+	switch {
+	case soft && !db.Status.IsConditionTrue(dbv1beta1.DatabaseQuotaWarning):
+		ev.warning(reasonQuotaWarning, "Database size %s is above the soft limit %s", usage.Size.String(), softLimit(quota).String())
+		db.Status.SetCondition(dbv1beta1.DatabaseQuotaWarning, v1.ConditionTrue, "SoftLimitReached",
+			fmt.Sprintf("Size %s is above the soft limit %s", usage.Size.String(), softLimit(quota).String()))
+	case !soft && db.Status.IsConditionTrue(dbv1beta1.DatabaseQuotaWarning):
+		db.Status.SetCondition(dbv1beta1.DatabaseQuotaWarning, v1.ConditionFalse, "BelowSoftLimit", "")
+	}
+
+	hard := quota != nil && size > quota.MaxSize.Value()
+	switch {
+	case hard && !db.Status.IsConditionTrue(dbv1beta1.DatabaseQuotaExceeded):
+		if err := srv.postgresRevokeAccess(db.Name); err != nil {
+			return err
+		}
+		ev.warning(reasonQuotaExceeded, "Database size %s is above the hard limit %s, access revoked", usage.Size.String(), quota.MaxSize.String())
+		db.Status.SetCondition(dbv1beta1.DatabaseQuotaExceeded, v1.ConditionTrue, "HardLimitReached",
+			fmt.Sprintf("Size %s is above the hard limit %s, CONNECT and CREATE are revoked until it drops", usage.Size.String(), quota.MaxSize.String()))
+	case !hard && db.Status.IsConditionTrue(dbv1beta1.DatabaseQuotaExceeded):
+		if err := srv.postgresRestoreAccess(db.Name); err != nil {
+			return err
+		}
+		ev.normal(reasonQuotaRestored, "Database size %s is within quota, access restored", usage.Size.String())
+		db.Status.SetCondition(dbv1beta1.DatabaseQuotaExceeded, v1.ConditionFalse, "BelowHardLimit", "")
+	}
+
+	return nil
+}
+
+func softLimit(quota *dbv1beta1.DatabaseSizeQuota) *resource.Quantity {
+	if quota.SoftMaxSize != nil {
+		return quota.SoftMaxSize
+	}
+	return resource.NewQuantity(quota.SoftLimit(), quota.MaxSize.Format)
+}
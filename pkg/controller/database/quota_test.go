@@ -0,0 +1,73 @@
+package database
+
+import (
+	"testing"
+
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSoftLimit(t *testing.T) {
+	soft := resource.MustParse("512Mi")
+	for _, c := range []struct {
+		quota dbv1beta1.DatabaseSizeQuota
+		want  string
+	}{
+		{dbv1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse("10Gi")}, "9Gi"},
+		{dbv1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse("10G")}, "9G"},
+		{dbv1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse("1Gi"), SoftMaxSize: &soft}, "512Mi"},
+	} {
+		if got := softLimit(&c.quota).String(); got != c.want {
+			t.Errorf("softLimit(%s) = %s, want %s", c.quota.MaxSize.String(), got, c.want)
+		}
+	}
+}
+
+// TestEnforceQuota covers the soft limit, changes of the hard limit condition revoke or
+// restore access on the server
+func TestEnforceQuota(t *testing.T) {
+	for _, c := range []struct {
+		name              string
+		size              string
+		quota             *dbv1beta1.DatabaseSizeQuota
+		warning, exceeded bool
+		wantWarning       v1.ConditionStatus
+		events            int
+	}{
+		{name: "no quota", size: "20Gi"},
+		{name: "below soft limit", size: "8Gi", quota: &dbv1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse("10Gi")}},
+		{name: "soft limit reached", size: "9500Mi", quota: &dbv1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse("10Gi")}, wantWarning: v1.ConditionTrue, events: 1},
+		{name: "soft limit still reached", size: "9500Mi", quota: &dbv1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse("10Gi")}, warning: true, wantWarning: v1.ConditionTrue},
+		{name: "below soft limit again", size: "8Gi", quota: &dbv1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse("10Gi")}, warning: true, wantWarning: v1.ConditionFalse},
+		{name: "quota removed", size: "9500Mi", warning: true, wantWarning: v1.ConditionFalse},
+		{name: "hard limit still reached", size: "11Gi", quota: &dbv1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse("10Gi")}, warning: true, exceeded: true, wantWarning: v1.ConditionTrue},
+	} {
+		db := &dbv1beta1.Database{Spec: dbv1beta1.DatabaseSpec{Quota: c.quota}}
+		if c.warning {
+			db.Status.SetCondition(dbv1beta1.DatabaseQuotaWarning, v1.ConditionTrue, "SoftLimitReached", "")
+		}
+		if c.exceeded {
+			db.Status.SetCondition(dbv1beta1.DatabaseQuotaExceeded, v1.ConditionTrue, "HardLimitReached", "")
+		}
+		recorder := record.NewFakeRecorder(10)
+		usage := dbv1beta1.DatabaseUsage{Size: resource.MustParse(c.size)}
+		// The server is only used when access is revoked or restored
+		if err := enforceQuota(nil, db, usage, eventer{recorder, db}); err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		var got v1.ConditionStatus
+		if cond := db.Status.GetCondition(dbv1beta1.DatabaseQuotaWarning); cond != nil {
+			got = cond.Status
+		}
+		if got != c.wantWarning {
+			t.Errorf("%s: QuotaWarning is %q, want %q", c.name, got, c.wantWarning)
+		}
+		if len(recorder.Events) != c.events {
+			t.Errorf("%s: %d events recorded, want %d", c.name, len(recorder.Events), c.events)
+		}
+	}
+}
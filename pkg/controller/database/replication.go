@@ -0,0 +1,272 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Migrations copy the schema with pg_dump and the data with logical replication: the source
+// publishes all tables of the database and the target subscribes to them. Writes on the source
+// only have to be blocked until the target has replayed the last changes.
+
+var replicationNameChars = regexp.MustCompile(`[^a-z0-9_]`)
+
+// replicationName is the name of the publication, subscription and replication slot of a
+// migration, slot names may only contain lower case letters, digits and underscores
+func replicationName(database string) string {
+	name := "migrate_" + replicationNameChars.ReplaceAllString(strings.ToLower(database), "_")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// postgresCheckPublish reports why the database can't be replicated from the server
+func (s *server) postgresCheckPublish(database string) error {
+	var walLevel string
+	if err := s.con.QueryRow(`SELECT current_setting('wal_level')`).Scan(&walLevel); err != nil {
+		return err
+	}
+	if walLevel != "logical" {
+		return fmt.Errorf("server %s needs wal_level logical to move databases, it's %s", s.Name, walLevel)
+	}
+
+	con, err := s.connectTo(database)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+	// Large objects aren't replicated
+	var largeObjects bool
+	if err := con.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_largeobject_metadata)`).Scan(&largeObjects); err != nil {
+		return err
+	}
+	if largeObjects {
+		return fmt.Errorf("database %s has large objects, they can't be moved to another server", database)
+	}
+	return nil
+}
+
+// postgresPublish publishes all tables of the database. Tables without a primary key get
+// their whole rows as replica identity, otherwise updates and deletes would fail on them.
+func (s *server) postgresPublish(database string) error {
+	con, err := s.connectTo(database)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+
+	rows, err := con.Query(`SELECT format('%I.%I', n.nspname, c.relname) FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND c.relreplident = 'd' AND c.relpersistence = 'p'
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'
+			AND NOT EXISTS (SELECT 1 FROM pg_index i WHERE i.indrelid = c.oid AND i.indisprimary)`)
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if _, err := con.Exec(fmt.Sprintf(`ALTER TABLE %s REPLICA IDENTITY FULL`, table)); err != nil {
+			log.Error(err, "Unable to set replica identity", "Database:", database, "Table:", table)
+			return err
+		}
+	}
+
+	name := replicationName(database)
+	var exists bool
+	if err := con.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_publication WHERE pubname = $1)`, name).Scan(&exists); err != nil || exists {
+		return err
+	}
+	_, err = con.Exec(fmt.Sprintf(`CREATE PUBLICATION "%s" FOR ALL TABLES`, name))
+	return err
+}
+
+// postgresUnpublish drops the publication of a migration and its replication slot, if the
+// subscription didn't drop it already
+func (s *server) postgresUnpublish(database string) error {
+	name := replicationName(database)
+	var active bool
+	err := s.con.QueryRow(`SELECT active FROM pg_replication_slots WHERE slot_name = $1`, name).Scan(&active)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return err
+	case active:
+		// A slot left behind keeps WAL on the server forever, it's dropped on the next attempt
+		return fmt.Errorf("replication slot %s on server %s is still in use", name, s.Name)
+	default:
+		if _, err := s.con.Exec(`SELECT pg_drop_replication_slot($1)`, name); err != nil {
+			return err
+		}
+	}
+
+	exists, err := s.postgresExists(database)
+	if err != nil || !exists {
+		return err
+	}
+	con, err := s.connectTo(database)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+	_, err = con.Exec(fmt.Sprintf(`DROP PUBLICATION IF EXISTS "%s"`, name))
+	return err
+}
+
+// postgresSubscribe subscribes the database to the publication on source, which copies the
+// existing rows and then streams the changes
+func (s *server) postgresSubscribe(database string, source *server) error {
+	con, err := s.connectTo(database)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+
+	name := replicationName(database)
+	var exists bool
+	if err := con.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_subscription WHERE subname = $1)`, name).Scan(&exists); err != nil || exists {
+		return err
+	}
+	_, err = con.Exec(fmt.Sprintf(`CREATE SUBSCRIPTION "%s" CONNECTION %s PUBLICATION "%s"`,
+		name, pq.QuoteLiteral(source.dsn(database)), name))
+	return err
+}
+
+// postgresUnsubscribe drops the subscription of a migration. If the source can't be reached
+// its replication slot is left to postgresUnpublish.
+func (s *server) postgresUnsubscribe(database string) error {
+	exists, err := s.postgresExists(database)
+	if err != nil || !exists {
+		return err
+	}
+	con, err := s.connectTo(database)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+
+	name := replicationName(database)
+	if err := con.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_subscription WHERE subname = $1)`, name).Scan(&exists); err != nil || !exists {
+		return err
+	}
+	if _, err := con.Exec(fmt.Sprintf(`DROP SUBSCRIPTION "%s"`, name)); err == nil {
+		return nil
+	}
+	for _, query := range []string{
+		`ALTER SUBSCRIPTION "%s" DISABLE`,
+		`ALTER SUBSCRIPTION "%s" SET (slot_name = NONE)`,
+		`DROP SUBSCRIPTION "%s"`,
+	} {
+		if _, err := con.Exec(fmt.Sprintf(query, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postgresSubscriptionSynced reports if the rows which existed when the subscription was created
+// are copied, and how many tables the subscription replicates
+func (s *server) postgresSubscriptionSynced(database string) (bool, int, error) {
+	con, err := s.connectTo(database)
+	if err != nil {
+		return false, 0, err
+	}
+	defer con.Close()
+
+	var tables, syncing int
+	err = con.QueryRow(`SELECT count(*), count(*) FILTER (WHERE r.srsubstate NOT IN ('r', 's'))
+		FROM pg_subscription_rel r JOIN pg_subscription s ON s.oid = r.srsubid
+		WHERE s.subname = $1`, replicationName(database)).Scan(&tables, &syncing)
+	return syncing == 0, tables, err
+}
+
+// postgresPublishedTables counts the tables the source publishes, tables created after the
+// subscription are published but not replicated
+func (s *server) postgresPublishedTables(database string) (int, error) {
+	con, err := s.connectTo(database)
+	if err != nil {
+		return 0, err
+	}
+	defer con.Close()
+
+	var tables int
+	err = con.QueryRow(`SELECT count(*) FROM pg_publication_tables WHERE pubname = $1`, replicationName(database)).Scan(&tables)
+	return tables, err
+}
+
+// postgresCurrentLSN returns the current WAL position of the server
+func (s *server) postgresCurrentLSN() (string, error) {
+	var lsn string
+	err := s.con.QueryRow(`SELECT pg_current_wal_lsn()::text`).Scan(&lsn)
+	return lsn, err
+}
+
+// postgresCaughtUp reports if the subscriber of the migration confirmed all changes up to lsn
+func (s *server) postgresCaughtUp(database, lsn string) (bool, error) {
+	var caughtUp bool
+	err := s.con.QueryRow(`SELECT confirmed_flush_lsn >= $2::pg_lsn FROM pg_replication_slots WHERE slot_name = $1`,
+		replicationName(database), lsn).Scan(&caughtUp)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("replication slot %s is missing on server %s", replicationName(database), s.Name)
+	}
+	return caughtUp, err
+}
+
+// postgresCopySequences sets the sequences of the database on target to their values on the
+// server, logical replication doesn't cover them
+func (s *server) postgresCopySequences(database string, target *server) error {
+	con, err := s.connectTo(database)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+
+	rows, err := con.Query(`SELECT format('%I.%I', schemaname, sequencename), last_value FROM pg_sequences
+		WHERE last_value IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	values := map[string]int64{}
+	for rows.Next() {
+		var sequence string
+		var value int64
+		if err := rows.Scan(&sequence, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		values[sequence] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	targetCon, err := target.connectTo(database)
+	if err != nil {
+		return err
+	}
+	defer targetCon.Close()
+	for sequence, value := range values {
+		if _, err := targetCon.Exec(`SELECT setval($1::regclass, $2)`, sequence, value); err != nil {
+			log.Error(err, "Unable to copy sequence", "Database:", database, "Sequence:", sequence)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/backup"
+	"db-operator/pkg/jobs"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// restore restores the backup in spec.restoreFrom into the provisioned database:
+//  1. access to the database is revoked and open sessions are terminated
+//  2. a Job runs pg_restore, objects which exist in the backup are replaced
+//  3. the objects are handed to the _owners role and access is given back
+//
+// running is true while the Job runs and the database has to be reconciled again.
+func (r *ReconcileDatabase) restore(reqLogger logr.Logger, srv *server, db *dbv1beta1.Database, ev eventer) (running bool, err error) {
+	from, st := db.Spec.RestoreFrom, db.Status.Restore
+	if db.Status.Phase != "Created" {
+		return false, nil
+	}
+	if from == nil {
+		// Forgetting the last restore allows setting restoreFrom again to retry it
+		if st != nil && st.Phase != dbv1beta1.RestoreRunning {
+			db.Status.Restore = nil
+		}
+		return false, nil
+	}
+	if st != nil && st.Backup == from.BackupName && st.Phase != dbv1beta1.RestoreRunning {
+		return false, nil
+	}
+
+	b := &dbv1beta1.DatabaseBackup{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: db.Namespace, Name: from.BackupName}, b); err != nil {
+		if errors.IsNotFound(err) {
+			return false, fmt.Errorf("backup %s not found", from.BackupName)
+		}
+		return false, err
+	}
+	switch b.Status.Phase {
+	case dbv1beta1.BackupCompleted:
+	case dbv1beta1.BackupFailed:
+		return false, fmt.Errorf("backup %s failed, it can't be restored", b.Name)
+	default:
+		// The DatabaseBackup watch reconciles the database again once it completes
+		db.Status.SetCondition(dbv1beta1.DatabaseRestored, v1.ConditionFalse, "WaitingForBackup",
+			fmt.Sprintf("Backup %s is not completed yet", b.Name))
+		return false, nil
+	}
+	storage, err := backup.StorageFor(b.Status.Storage)
+	if err != nil {
+		return false, err
+	}
+
+	name := jobs.Name("restore", db)
+	if st == nil || st.Backup != b.Name {
+		reqLogger.Info("Restoring Database", "Backup", b.Name)
+		// A Job left over by a previous restore would report its outcome
+		if err := r.jobs.Cleanup(name); err != nil {
+			return false, err
+		}
+		if err := srv.postgresRevokeAccess(db.Name); err != nil {
+			return false, err
+		}
+		db.Status.Restore = &dbv1beta1.DatabaseRestoreStatus{
+			Backup:    b.Name,
+			Location:  b.Status.Location,
+			Phase:     dbv1beta1.RestoreRunning,
+			StartTime: metav1.Now(),
+		}
+		db.Status.SetCondition(dbv1beta1.DatabaseRestored, v1.ConditionFalse, string(dbv1beta1.RestoreRunning),
+			fmt.Sprintf("Restoring backup %s, access to the database is blocked", b.Name))
+		ev.normal(reasonRestoring, "Restoring backup %s from %s", b.Name, b.Status.Location)
+		st = db.Status.Restore
+	}
+
+	state, err := r.jobs.Run(name, db, storage.RestoreJob(srv.dsn(db.Name), backup.Key(b)))
+	if err != nil {
+		return false, err
+	}
+	if state == jobs.Running {
+		return true, nil
+	}
+
+	now := metav1.Now()
+	st.CompletionTime = &now
+	if state == jobs.Failed {
+		// The Job is kept for its logs until the next restore
+		st.Phase = dbv1beta1.RestoreFailed
+		db.Status.SetCondition(dbv1beta1.DatabaseRestored, v1.ConditionFalse, string(dbv1beta1.RestoreFailed),
+			fmt.Sprintf("Restoring backup %s failed, see Job %s/%s", b.Name, r.jobs.Namespace, name))
+		ev.warning(reasonRestoreFailed, "Restoring backup %s failed", b.Name)
+		return false, r.giveBackAccess(srv, db)
+	}
+
+	if err := srv.postgresOwnObjects(db.Name); err != nil {
+		return false, err
+	}
+	if err := r.giveBackAccess(srv, db); err != nil {
+		return false, err
+	}
+	st.Phase = dbv1beta1.RestoreCompleted
+	db.Status.SetCondition(dbv1beta1.DatabaseRestored, v1.ConditionTrue, string(dbv1beta1.RestoreCompleted),
+		fmt.Sprintf("Restored backup %s", b.Name))
+	ev.normal(reasonRestored, "Restored backup %s", b.Name)
+	return false, r.jobs.Cleanup(name)
+}
+
+// giveBackAccess restores the privileges revoked for the restore, unless the size quota
+// of the database keeps them revoked
+func (r *ReconcileDatabase) giveBackAccess(srv *server, db *dbv1beta1.Database) error {
+	if db.Status.IsConditionTrue(dbv1beta1.DatabaseQuotaExceeded) {
+		return nil
+	}
+	return srv.postgresRestoreAccess(db.Name)
+}
+
+// restoringDatabases returns the Databases waiting for or restoring the backup
+func restoringDatabases(c client.Client, ns, backup string) []reconcile.Request {
+	list := &dbv1beta1.DatabaseList{}
+	if err := c.List(context.TODO(), &client.ListOptions{Namespace: ns}, list); err != nil {
+		log.Error(err, "Unable to list Databases", "Namespace", ns)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, db := range list.Items {
+		if db.Spec.RestoreFrom != nil && db.Spec.RestoreFrom.BackupName == backup {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: db.Namespace, Name: db.Name}})
+		}
+	}
+	return requests
+}
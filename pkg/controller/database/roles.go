@@ -0,0 +1,176 @@
+package database
+
+import (
+	"db-operator/pkg/apis/db/v1beta1"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// postgresUpdateRoles reconciles the settings of the database user and of spec.users like
+// postgresUpdateGrants does with their grants. The user of the database goes back to no
+// connection limit and no expiry once they're removed from spec.userSettings. spec.users are
+// shared roles, only their parameters in the database are set. Parameters in the database are
+// reset for all of them, and for former users the operator set parameters for.
+func (s *server) postgresUpdateRoles(db *v1beta1.Database, ev eventer) error {
+	roles := map[string]*v1beta1.RoleSettings{db.Name: db.Spec.UserSettings}
+	for i := range db.Spec.Users {
+		roles[db.Spec.Users[i].Name] = &db.Spec.Users[i].RoleSettings
+	}
+	if roles[db.Name] == nil {
+		roles[db.Name] = &v1beta1.RoleSettings{}
+	}
+	names := make([]string, 0, len(roles))
+	for name := range roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parameters, err := s.postgresParameters(db.Name)
+	if err != nil {
+		return err
+	}
+
+	var refused, withParameters []string
+	for _, role := range names {
+		denied, err := s.postgresUpdateRole(db, role, roles[role], role == db.Name, parameters[role], ev)
+		if err != nil {
+			return err
+		}
+		for _, name := range denied {
+			refused = append(refused, fmt.Sprintf("%s of user %s", name, role))
+		}
+		if len(roles[role].Parameters) > len(denied) {
+			withParameters = append(withParameters, role)
+		}
+	}
+
+	for _, role := range db.Status.RolesWithParameters {
+		if _, ok := roles[role]; ok || len(parameters[role]) == 0 {
+			continue
+		}
+		query := fmt.Sprintf(`ALTER ROLE "%s" IN DATABASE "%s" RESET ALL`, role, db.Name)
+		if _, err := s.exec(opAlter, query); err != nil {
+			return err
+		}
+		ev.normal(reasonAltered, "Parameters of role %s reset", role)
+	}
+
+	db.Status.RolesWithParameters = withParameters
+	var msgs []string
+	reason := "ParameterNotAllowed"
+	if len(refused) > 0 {
+		msgs = append(msgs, fmt.Sprintf("Parameters %s may not be set on server %s", strings.Join(refused, ", "), s.Name))
+	}
+	// The webhook refuses them, roles of others are never altered on the server
+	if refusal := db.RoleSettingsRefusal(); refusal != "" {
+		if len(msgs) == 0 {
+			reason = "RoleNotManaged"
+		}
+		msgs = append(msgs, refusal)
+	}
+	if len(msgs) > 0 {
+		msg := strings.Join(msgs, "; ")
+		if c := db.Status.GetCondition(v1beta1.DatabaseRoleSettingsApplied); c == nil || c.Message != msg {
+			ev.warning(reasonRefused, "%s", msg)
+		}
+		db.Status.SetCondition(v1beta1.DatabaseRoleSettingsApplied, corev1.ConditionFalse, reason, msg)
+	} else if db.Status.GetCondition(v1beta1.DatabaseRoleSettingsApplied) != nil {
+		db.Status.SetCondition(v1beta1.DatabaseRoleSettingsApplied, corev1.ConditionTrue, "Applied", "")
+	}
+	return nil
+}
+
+// postgresUpdateRole alters a single role. The attributes of owned roles are set too, other
+// roles only get their parameters in the database as their attributes apply on the whole server.
+// refused are the parameters which may not be set.
+func (s *server) postgresUpdateRole(db *v1beta1.Database, role string, settings *v1beta1.RoleSettings, owned bool,
+	current map[string]string, ev eventer) (refused []string, err error) {
+	if owned {
+		if err := s.postgresUpdateRoleAttributes(role, settings, ev); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(settings.Parameters))
+	for name := range settings.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	desired := map[string]bool{}
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if !s.allowsParameter(key) {
+			refused = append(refused, name)
+			continue
+		}
+		desired[key] = true
+		value := parameterValue(key, settings.Parameters[name])
+		if was, set := current[key]; set && was == value {
+			continue
+		}
+		query := fmt.Sprintf(`ALTER ROLE "%s" IN DATABASE "%s" SET %s = %s`, role, db.Name, key, parameterLiteral(key, value))
+		if _, err := s.exec(opAlter, query); err != nil {
+			return nil, err
+		}
+		ev.normal(reasonAltered, "Parameter %s of role %s set to %q", key, role, value)
+	}
+	for key := range current {
+		if desired[key] {
+			continue
+		}
+		query := fmt.Sprintf(`ALTER ROLE "%s" IN DATABASE "%s" RESET %s`, role, db.Name, key)
+		if _, err := s.exec(opAlter, query); err != nil {
+			return nil, err
+		}
+		ev.normal(reasonAltered, "Parameter %s of role %s reset", key, role)
+	}
+	return refused, nil
+}
+
+// postgresUpdateRoleAttributes sets the connection limit and expiry of a role the operator
+// created, attributes left out of settings are reset to no limit and no expiry
+func (s *server) postgresUpdateRoleAttributes(role string, settings *v1beta1.RoleSettings, ev eventer) error {
+	var connectionLimit int32
+	var validUntil float64
+	query := `SELECT rolconnlimit, COALESCE(extract(epoch FROM rolvaliduntil)::float8, 'Infinity'::float8)
+		FROM pg_roles WHERE rolname = $1`
+	if err := s.con.QueryRow(query, role).Scan(&connectionLimit, &validUntil); err != nil {
+		log.Error(err, "Unable to read role", "Role:", role)
+		return err
+	}
+
+	limit := int32(-1)
+	if settings.ConnectionLimit != nil {
+		limit = *settings.ConnectionLimit
+	}
+	if limit != connectionLimit {
+		query := fmt.Sprintf(`ALTER ROLE "%s" CONNECTION LIMIT %d`, role, limit)
+		if _, err := s.exec(opAlter, query); err != nil {
+			return err
+		}
+		ev.normal(reasonAltered, "Connection limit of role %s set to %d", role, limit)
+	}
+
+	switch {
+	case settings.ValidUntil != nil && int64(validUntil) != settings.ValidUntil.Unix():
+		until := settings.ValidUntil.UTC().Format(time.RFC3339)
+		query := fmt.Sprintf(`ALTER ROLE "%s" VALID UNTIL '%s'`, role, until)
+		if _, err := s.exec(opAlter, query); err != nil {
+			return err
+		}
+		ev.normal(reasonAltered, "Role %s is valid until %s", role, until)
+	case settings.ValidUntil == nil && !math.IsInf(validUntil, 1):
+		query := fmt.Sprintf(`ALTER ROLE "%s" VALID UNTIL 'infinity'`, role)
+		if _, err := s.exec(opAlter, query); err != nil {
+			return err
+		}
+		ev.normal(reasonAltered, "Role %s doesn't expire anymore", role)
+	}
+	return nil
+}
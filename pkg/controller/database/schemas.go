@@ -0,0 +1,162 @@
+package database
+
+import (
+	"database/sql"
+	"db-operator/pkg/apis/db/v1beta1"
+	"fmt"
+	"time"
+)
+
+// privileges on a schema and on each kind of object in it
+type privileges struct {
+	schema, tables, sequences, functions string
+}
+
+// schemaPrivileges are the privileges of the access levels
+var schemaPrivileges = map[v1beta1.SchemaAccess]privileges{
+	v1beta1.SchemaRead:      {"USAGE", "SELECT", "SELECT", "EXECUTE"},
+	v1beta1.SchemaReadWrite: {"USAGE", "SELECT, INSERT, UPDATE, DELETE, TRUNCATE", "USAGE, SELECT, UPDATE", "EXECUTE"},
+	v1beta1.SchemaAll:       {"ALL", "ALL", "ALL", "ALL"},
+}
+
+// postgresUpdateSchemas creates the schemas in spec.schemas, hands them to the _owners role and
+// grants access to them. Grants which were removed from the spec are revoked, schemas removed
+// from the spec are dropped if spec.pruneSchemas is set and forgotten otherwise.
+func (s *server) postgresUpdateSchemas(db *v1beta1.Database, ev eventer) error {
+	if len(db.Spec.Schemas) == 0 && len(db.Status.Schemas) == 0 {
+		return nil
+	}
+	// A copy of the database is about to replace the content, it brings its own schemas
+	if st := db.Status.Clone; st != nil && st.Phase == v1beta1.RestoreRunning {
+		return nil
+	}
+	if st := db.Status.Restore; st != nil && st.Phase == v1beta1.RestoreRunning {
+		return nil
+	}
+
+	roleName := fmt.Sprintf(`%s_owners`, db.Name)
+	// Handing schemas over requires membership in the new owner
+	if _, err := s.exec(opGrant, fmt.Sprintf(`GRANT "%s" TO CURRENT_USER`, roleName)); err != nil {
+		return err
+	}
+
+	con, err := s.connectTo(db.Name)
+	if err != nil {
+		return err
+	}
+	defer con.Close()
+
+	start := time.Now()
+	defer func() {
+		sqlDuration.WithLabelValues(s.Type, s.Name, opSchema).Observe(time.Since(start).Seconds())
+	}()
+
+	applied := map[string]v1beta1.DatabaseSchema{}
+	for _, schema := range db.Status.Schemas {
+		applied[schema.Name] = schema
+	}
+
+	for _, schema := range db.Spec.Schemas {
+		var owner string
+		err := con.QueryRow(`SELECT pg_get_userbyid(nspowner) FROM pg_namespace WHERE nspname = $1`, schema.Name).Scan(&owner)
+		switch {
+		case err == sql.ErrNoRows:
+			if _, err := con.Exec(fmt.Sprintf(`CREATE SCHEMA "%s" AUTHORIZATION "%s"`, schema.Name, roleName)); err != nil {
+				log.Error(err, "Unable to create schema", "Database:", db.Name, "Schema:", schema.Name)
+				return err
+			}
+			ev.normal(reasonCreated, "Schema %s created", schema.Name)
+		case err != nil:
+			return err
+		case owner != roleName:
+			if _, err := con.Exec(fmt.Sprintf(`ALTER SCHEMA "%s" OWNER TO "%s"`, schema.Name, roleName)); err != nil {
+				log.Error(err, "Unable to change schema owner", "Database:", db.Name, "Schema:", schema.Name)
+				return err
+			}
+			ev.normal(reasonAltered, "Owner of schema %s set to %s", schema.Name, roleName)
+		}
+
+		for _, g := range revokedGrants(schema.Grants, applied[schema.Name].Grants) {
+			if err := schemaGrant(con, "REVOKE", schema.Name, roleName, g.Role, schemaPrivileges[v1beta1.SchemaAll]); err != nil {
+				log.Error(err, "Unable to revoke schema privileges", "Database:", db.Name, "Schema:", schema.Name, "Role:", g.Role)
+				return err
+			}
+			ev.normal(reasonRevoked, "Revoked %s access to schema %s from %s", g.Access, schema.Name, g.Role)
+		}
+		// Granting again covers objects created meanwhile by other roles than _owners
+		for _, g := range schema.Grants {
+			if err := schemaGrant(con, "GRANT", schema.Name, roleName, g.Role, schemaPrivileges[g.Access]); err != nil {
+				log.Error(err, "Unable to grant schema privileges", "Database:", db.Name, "Schema:", schema.Name, "Role:", g.Role)
+				return err
+			}
+			if previous, ok := applied[schema.Name]; !ok || !hasGrant(previous.Grants, g) {
+				ev.normal(reasonGranted, "Granted %s access to schema %s to %s", g.Access, schema.Name, g.Role)
+			}
+		}
+		delete(applied, schema.Name)
+	}
+
+	for name := range applied {
+		if !db.Spec.PruneSchemas {
+			log.Info("Schema is not managed anymore", "Database:", db.Name, "Schema:", name)
+			continue
+		}
+		if _, err := con.Exec(fmt.Sprintf(`DROP SCHEMA IF EXISTS "%s" CASCADE`, name)); err != nil {
+			log.Error(err, "Unable to drop schema", "Database:", db.Name, "Schema:", name)
+			return err
+		}
+		ev.normal(reasonDropped, "Schema %s dropped", name)
+	}
+
+	db.Status.Schemas = nil
+	for _, schema := range db.Spec.Schemas {
+		db.Status.Schemas = append(db.Status.Schemas, *schema.DeepCopy())
+	}
+	return nil
+}
+
+// schemaGrant grants or revokes the privileges on the schema and the objects in it, including
+// the default privileges for objects the owner creates later
+func schemaGrant(con *sql.DB, action, schema, owner, role string, p privileges) error {
+	to := "TO"
+	if action == "REVOKE" {
+		to = "FROM"
+	}
+	queries := []string{
+		fmt.Sprintf(`%s %s ON SCHEMA "%s" %s "%s"`, action, p.schema, schema, to, role),
+		fmt.Sprintf(`%s %s ON ALL TABLES IN SCHEMA "%s" %s "%s"`, action, p.tables, schema, to, role),
+		fmt.Sprintf(`%s %s ON ALL SEQUENCES IN SCHEMA "%s" %s "%s"`, action, p.sequences, schema, to, role),
+		fmt.Sprintf(`%s %s ON ALL FUNCTIONS IN SCHEMA "%s" %s "%s"`, action, p.functions, schema, to, role),
+		fmt.Sprintf(`ALTER DEFAULT PRIVILEGES FOR ROLE "%s" IN SCHEMA "%s" %s %s ON TABLES %s "%s"`, owner, schema, action, p.tables, to, role),
+		fmt.Sprintf(`ALTER DEFAULT PRIVILEGES FOR ROLE "%s" IN SCHEMA "%s" %s %s ON SEQUENCES %s "%s"`, owner, schema, action, p.sequences, to, role),
+		fmt.Sprintf(`ALTER DEFAULT PRIVILEGES FOR ROLE "%s" IN SCHEMA "%s" %s %s ON FUNCTIONS %s "%s"`, owner, schema, action, p.functions, to, role),
+	}
+	for _, query := range queries {
+		if _, err := con.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasGrant reports if grants contain g with the same access
+func hasGrant(grants []v1beta1.SchemaGrant, g v1beta1.SchemaGrant) bool {
+	for _, granted := range grants {
+		if granted == g {
+			return true
+		}
+	}
+	return false
+}
+
+// revokedGrants are the applied grants which aren't in grants with the same access anymore.
+// All privileges are revoked from them, grants whose access changed are granted again.
+func revokedGrants(grants, applied []v1beta1.SchemaGrant) []v1beta1.SchemaGrant {
+	var revoked []v1beta1.SchemaGrant
+	for _, g := range applied {
+		if !hasGrant(grants, g) {
+			revoked = append(revoked, g)
+		}
+	}
+	return revoked
+}
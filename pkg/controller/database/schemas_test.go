@@ -0,0 +1,45 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"db-operator/pkg/apis/db/v1beta1"
+)
+
+func TestRevokedGrants(t *testing.T) {
+	read := v1beta1.SchemaGrant{Role: "reader", Access: v1beta1.SchemaRead}
+	readWrite := v1beta1.SchemaGrant{Role: "reader", Access: v1beta1.SchemaReadWrite}
+	all := v1beta1.SchemaGrant{Role: "admin", Access: v1beta1.SchemaAll}
+
+	for _, c := range []struct {
+		name            string
+		grants, applied []v1beta1.SchemaGrant
+		want            []v1beta1.SchemaGrant
+	}{
+		{"new schema", []v1beta1.SchemaGrant{read}, nil, nil},
+		{"unchanged", []v1beta1.SchemaGrant{read, all}, []v1beta1.SchemaGrant{all, read}, nil},
+		{"grant added", []v1beta1.SchemaGrant{read, all}, []v1beta1.SchemaGrant{read}, nil},
+		{"grant removed", []v1beta1.SchemaGrant{read}, []v1beta1.SchemaGrant{read, all}, []v1beta1.SchemaGrant{all}},
+		{"access changed", []v1beta1.SchemaGrant{readWrite}, []v1beta1.SchemaGrant{read}, []v1beta1.SchemaGrant{read}},
+		{"all removed", nil, []v1beta1.SchemaGrant{read, all}, []v1beta1.SchemaGrant{read, all}},
+	} {
+		if got := revokedGrants(c.grants, c.applied); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: revokedGrants = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSchemaPrivileges(t *testing.T) {
+	// Every access level of the CRD has privileges, none of them but All may change the schema
+	for _, access := range []v1beta1.SchemaAccess{v1beta1.SchemaRead, v1beta1.SchemaReadWrite, v1beta1.SchemaAll} {
+		p, ok := schemaPrivileges[access]
+		if !ok {
+			t.Errorf("no privileges for %s access", access)
+			continue
+		}
+		if access != v1beta1.SchemaAll && p.schema != "USAGE" {
+			t.Errorf("%s access grants %s on the schema", access, p.schema)
+		}
+	}
+}
@@ -1,24 +1,23 @@
 package database
 
 import (
-	"db-operator/pkg/apis/db/v1alpha1"
+	"db-operator/pkg/apis/db/v1beta1"
 	"fmt"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-
-	"github.com/spf13/viper"
+	"strconv"
 )
 
-func updateSecret(db *v1alpha1.Database, usr *user) (*corev1.Secret, error) {
+func updateSecret(srv *server, db *v1beta1.Database, usr *user) (*corev1.Secret, error) {
 	var err error
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-db-secret", db.Name),
+			Name:      secretName(db),
 			Namespace: db.Namespace,
 		},
 		Data: map[string][]byte{
-			"database-host":     []byte(viper.GetString("dbHost")),
-			"database-port":     []byte("5432"),
+			"database-host":     []byte(srv.Host),
+			"database-port":     []byte(strconv.Itoa(srv.Port)),
 			"database-name":     []byte(db.Name),
 			"database-user":     []byte(usr.username),
 			"database-password": []byte(usr.password),
@@ -27,3 +26,8 @@ func updateSecret(db *v1alpha1.Database, usr *user) (*corev1.Secret, error) {
 
 	return secret, err
 }
+
+// secretName is the name of the Secret with the credentials of the database
+func secretName(db *v1beta1.Database) string {
+	return fmt.Sprintf("%s-db-secret", db.Name)
+}
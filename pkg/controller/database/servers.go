@@ -0,0 +1,223 @@
+package database
+
+import (
+	"database/sql"
+	"db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/backup"
+	"db-operator/pkg/util"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const defaultServerName = "default"
+
+// server is a database server the operator manages databases on.
+// Servers are read from the "servers" list of the operator config, the legacy
+// top level db* keys define a server named "default".
+type server struct {
+	Name     string `mapstructure:"name"`
+	Type     string `mapstructure:"type"`
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	Database string `mapstructure:"database"`
+	// AllowedNamespaces and NamespaceSelector restrict which namespaces may create databases
+	// on the server. A namespace matching either is allowed, all are allowed if neither is set.
+	AllowedNamespaces []string `mapstructure:"allowedNamespaces"`
+	NamespaceSelector string   `mapstructure:"namespaceSelector"`
+	// Labels are matched by spec.serverSelector of Databases which are placed automatically
+	Labels map[string]string `mapstructure:"labels"`
+	// Capacity limits how many databases are placed on the server
+	Capacity serverCapacity `mapstructure:"capacity"`
+	// Templates are the databases which may be cloned with spec.cloneFrom.template
+	Templates []string `mapstructure:"templates"`
+	// AllowedParameters replace the allowedParameters of the operator for spec.parameters
+	AllowedParameters []string `mapstructure:"allowedParameters"`
+	// AllowedExtensions replace the allowedExtensions of the operator for spec.extensions
+	AllowedExtensions []string `mapstructure:"allowedExtensions"`
+
+	con      *sql.DB
+	selector labels.Selector
+}
+
+// serverCapacity is how much the server is meant to hold, placement skips or avoids full servers
+type serverCapacity struct {
+	// Databases is the number of databases on the server, templates and the postgres database
+	// aside, no more are placed once it's reached
+	Databases int `mapstructure:"databases"`
+	// Size is the disk space, e.g. 500Gi, servers with less free space are preferred
+	Size string `mapstructure:"size"`
+
+	size int64
+}
+
+var (
+	servers       = map[string]*server{}
+	defaultServer string
+)
+
+// Each database type will have it's own config file
+func init() {
+	viper.AddConfigPath(".")
+	viper.AddConfigPath("/config")
+	viper.SetConfigName("postgres")
+	viper.SetDefault("defaultServer", defaultServerName)
+	log.Info("Initializing postgresql config")
+
+	err := viper.ReadInConfig()
+	if err != nil {
+		log.Error(err, "Failed to read config")
+		os.Exit(1)
+	}
+
+	var configured []*server
+	if viper.IsSet("dbHost") {
+		configured = append(configured, &server{
+			Name:     defaultServerName,
+			Host:     viper.GetString("dbHost"),
+			User:     viper.GetString("dbUser"),
+			Password: viper.GetString("dbPassword"),
+			Database: viper.GetString("dbDatabase"),
+		})
+	}
+	var listed []*server
+	if err := viper.UnmarshalKey("servers", &listed); err != nil {
+		log.Error(err, "Failed to read servers from config")
+		os.Exit(1)
+	}
+	configured = append(configured, listed...)
+	defaultServer = viper.GetString("defaultServer")
+
+	for _, s := range configured {
+		if s.Type == "" {
+			s.Type = "postgres"
+		}
+		if s.Port == 0 {
+			s.Port = 5432
+		}
+		if s.Database == "" {
+			s.Database = "postgres"
+		}
+		if s.Capacity.Size != "" {
+			size, err := resource.ParseQuantity(s.Capacity.Size)
+			if err != nil {
+				log.Error(err, "Invalid capacity size", "Server", s.Name)
+				os.Exit(1)
+			}
+			s.Capacity.size = size.Value()
+		}
+		if s.NamespaceSelector != "" {
+			if s.selector, err = labels.Parse(s.NamespaceSelector); err != nil {
+				log.Error(err, "Invalid namespace selector", "Server", s.Name)
+				os.Exit(1)
+			}
+		}
+		s.connect()
+		servers[s.Name] = s
+	}
+
+	readPlacement()
+	backup.ReadStorages()
+}
+
+// dsn is the connection string for database on the server
+func (s *server) dsn(database string) string {
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return fmt.Sprintf("dbname='%s' user='%s' password='%s' host='%s' port=%d sslmode=disable",
+		quote.Replace(database), quote.Replace(s.User), quote.Replace(s.Password), quote.Replace(s.Host), s.Port)
+}
+
+// connectTo opens a connection to a database other than the admin one, the caller closes it
+func (s *server) connectTo(database string) (*sql.DB, error) {
+	con, err := sql.Open("postgres", s.dsn(database))
+	if err != nil {
+		return nil, err
+	}
+	if err := con.Ping(); err != nil {
+		con.Close()
+		return nil, err
+	}
+	return con, nil
+}
+
+func (s *server) connect() {
+	var err error
+	s.con, err = sql.Open("postgres", s.dsn(s.Database))
+	if err != nil {
+		log.Error(err, "Unable to connect to the database", "Server", s.Name)
+	}
+
+	if err = s.con.Ping(); err != nil {
+		log.Error(err, "Unable to access database", "Server", s.Name)
+	}
+}
+
+// allows reports if Databases in ns may be placed on the server
+func (s *server) allows(ns *corev1.Namespace) bool {
+	if len(s.AllowedNamespaces) == 0 && s.selector == nil {
+		return true
+	}
+	return util.Contains(s.AllowedNamespaces, ns.Name) || (s.selector != nil && s.selector.Matches(labels.Set(ns.Labels)))
+}
+
+// serverFor returns the server the database lives on. The server is pinned in its status once
+// the database is scheduled, before that spec.serverRef is used. Databases created before
+// servers were pinned live on the default server. A serverRef which differs from the pinned
+// server is handled by migrate.
+func serverFor(db *v1beta1.Database) (*server, error) {
+	name := serverName(db)
+	s, ok := servers[name]
+	if !ok {
+		return nil, fmt.Errorf("server %s is not configured", name)
+	}
+	return s, nil
+}
+
+// DSN returns the server a provisioned Database is on and the admin connection string for it
+func DSN(db *v1beta1.Database) (server, dsn string, err error) {
+	srv, err := serverFor(db)
+	if err != nil {
+		return "", "", err
+	}
+	return srv.Name, srv.dsn(db.Name), nil
+}
+
+// pinnedServer returns the name of the server a database is pinned to. Databases created by
+// releases without multiple servers carry the host of the server in status.server instead.
+func pinnedServer(name string) string {
+	if _, ok := servers[name]; ok {
+		return name
+	}
+	// That was always the host of the default server
+	if s, ok := servers[defaultServerName]; ok && s.Host == name {
+		return s.Name
+	}
+	for _, s := range servers {
+		if s.Host == name {
+			return s.Name
+		}
+	}
+	return name
+}
+
+// serverName returns the name of the server the database lives or will live on,
+// it's empty while the database waits to be scheduled
+func serverName(db *v1beta1.Database) string {
+	switch {
+	case db.Status.Server != "":
+		return pinnedServer(db.Status.Server)
+	case db.Spec.ServerRef != nil:
+		return db.Spec.ServerRef.Name
+	case db.Status.Phase != "":
+		return defaultServer
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestAllows(t *testing.T) {
+	team := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team", Labels: map[string]string{"tier": "prod"}}}
+	other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"tier": "dev"}}}
+	unlabeled := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}}
+
+	for _, c := range []struct {
+		name       string
+		namespaces []string
+		selector   string
+		want       map[*corev1.Namespace]bool
+	}{
+		{"unrestricted", nil, "", map[*corev1.Namespace]bool{team: true, other: true, unlabeled: true}},
+		{"by name", []string{"team"}, "", map[*corev1.Namespace]bool{team: true, other: false, unlabeled: false}},
+		{"by selector", nil, "tier=prod", map[*corev1.Namespace]bool{team: true, other: false, unlabeled: false}},
+		{"by name or selector", []string{"unlabeled"}, "tier=prod", map[*corev1.Namespace]bool{team: true, other: false, unlabeled: true}},
+		{"by negated selector", nil, "tier!=prod", map[*corev1.Namespace]bool{team: false, other: true, unlabeled: true}},
+	} {
+		s := &server{Name: "db1", AllowedNamespaces: c.namespaces}
+		if c.selector != "" {
+			var err error
+			if s.selector, err = labels.Parse(c.selector); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for ns, want := range c.want {
+			if got := s.allows(ns); got != want {
+				t.Errorf("%s: allows(%s) = %t, want %t", c.name, ns.Name, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const databaseCRDName = "databases.db.clarizen.cloud"
+
+// migrateStorageVersion rewrites Databases which are still stored in an old version.
+// Every object is written back unchanged, which makes the apiserver store it in the
+// current storage version. Afterwards only the storage version is left in the CRD
+// storedVersions, so the old version can be dropped from the CRD in a later release.
+func migrateStorageVersion(mgr manager.Manager) manager.Runnable {
+	return manager.RunnableFunc(func(stop <-chan struct{}) error {
+		// The manager cache isn't synced yet, talk to the apiserver directly
+		c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+		if err != nil {
+			return err
+		}
+
+		crd := &apiextensionsv1beta1.CustomResourceDefinition{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Name: databaseCRDName}, crd); err != nil {
+			log.Error(err, "Unable to get CRD, skipping storage version migration", "CRD", databaseCRDName)
+			return nil
+		}
+		storageVersion := dbv1beta1.SchemeGroupVersion.Version
+		if len(crd.Status.StoredVersions) == 1 && crd.Status.StoredVersions[0] == storageVersion {
+			return nil
+		}
+
+		log.Info("Migrating Databases to the storage version", "StoredVersions", crd.Status.StoredVersions, "StorageVersion", storageVersion)
+		list := &dbv1beta1.DatabaseList{}
+		if err := c.List(context.TODO(), &client.ListOptions{}, list); err != nil {
+			log.Error(err, "Unable to list Databases for storage version migration")
+			return nil
+		}
+		for i := range list.Items {
+			err := c.Update(context.TODO(), &list.Items[i])
+			if errors.IsConflict(err) || errors.IsNotFound(err) {
+				// Somebody else already wrote the object in the storage version
+				continue
+			}
+			if err != nil {
+				log.Error(err, "Unable to migrate Database", "Db.Namespace", list.Items[i].Namespace, "Db.Name", list.Items[i].Name)
+				return nil
+			}
+		}
+
+		crd.Status.StoredVersions = []string{storageVersion}
+		if err := c.Status().Update(context.TODO(), crd); err != nil {
+			log.Error(err, "Unable to update CRD stored versions", "CRD", databaseCRDName)
+			return nil
+		}
+		log.Info("Storage version migration finished", "Databases", len(list.Items))
+
+		return nil
+	})
+}
@@ -0,0 +1,209 @@
+package database
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/jobs"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// trashLabel marks the ConfigMaps recording soft deleted databases
+	trashLabel = "db.clarizen.cloud/trash"
+	// trashPurgeInterval is how often expired soft deleted databases are looked for
+	trashPurgeInterval = 10 * time.Minute
+	// maxTrashedName is how much of the database name is kept in the trash name,
+	// which must fit into the 63 characters of a postgres identifier
+	maxTrashedName = 46
+)
+
+func init() {
+	viper.SetDefault("trashRetention", "168h")
+}
+
+// trashBin keeps a record of soft deleted databases. The records are ConfigMaps in the operator
+// namespace, next to the Jobs, because the Database objects are gone.
+type trashBin struct {
+	client    client.Client
+	namespace string
+	retention time.Duration
+}
+
+func newTrashBin(runner *jobs.Runner) *trashBin {
+	return &trashBin{client: runner.Client, namespace: runner.Namespace, retention: viper.GetDuration("trashRetention")}
+}
+
+// trashName is the name of the database in the trash. It's derived from the deletion
+// timestamp, so that a finalizer which is retried renames it the same way.
+func trashName(db *dbv1beta1.Database) string {
+	name := db.Name
+	if len(name) > maxTrashedName {
+		name = name[:maxTrashedName]
+	}
+	deleted := time.Now()
+	if db.GetDeletionTimestamp() != nil {
+		deleted = db.GetDeletionTimestamp().Time
+	}
+	return fmt.Sprintf("trash_%s_%d", name, deleted.Unix())
+}
+
+// recordName is the name of the ConfigMap recording the database trash
+func recordName(trash string) string {
+	return strings.Replace(trash, "_", "-", -1)
+}
+
+// put soft deletes the database: it's recorded, renamed and its roles are dropped
+func (t *trashBin) put(srv *server, db *dbv1beta1.Database, ev eventer) error {
+	trash := trashName(db)
+	purgeAfter := time.Now().Add(t.retention).UTC().Format(time.RFC3339)
+
+	record := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      recordName(trash),
+			Namespace: t.namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "db-operator",
+				trashLabel:                     "true",
+				"db.clarizen.cloud/namespace":  db.Namespace,
+				"db.clarizen.cloud/name":       db.Name,
+			},
+		},
+		Data: map[string]string{
+			"server":     srv.Name,
+			"database":   trash,
+			"namespace":  db.Namespace,
+			"name":       db.Name,
+			"deletedAt":  time.Now().UTC().Format(time.RFC3339),
+			"purgeAfter": purgeAfter,
+		},
+	}
+	if err := t.client.Create(context.TODO(), record); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	if err := srv.postgresTrash(db.Name, trash); err != nil {
+		return err
+	}
+	// Only the roles are dropped for a policy other than Delete
+	if err := srv.postgresDeleteEvent(db, ev); err != nil {
+		return err
+	}
+
+	ev.normal(reasonTrashed, "Database %s moved to %s on server %s, it's purged after %s. "+
+		"Create the Database again with the %s annotation to undelete it.",
+		db.Name, trash, srv.Name, purgeAfter, dbv1beta1.UndeleteAnnotation)
+	return nil
+}
+
+// find returns the latest record of the database, nil if there's none
+func (t *trashBin) find(db *dbv1beta1.Database) (*corev1.ConfigMap, error) {
+	list := &corev1.ConfigMapList{}
+	opts := client.InNamespace(t.namespace).MatchingLabels(map[string]string{
+		trashLabel:                    "true",
+		"db.clarizen.cloud/namespace": db.Namespace,
+		"db.clarizen.cloud/name":      db.Name,
+	})
+	if err := t.client.List(context.TODO(), opts, list); err != nil {
+		return nil, err
+	}
+
+	var latest *corev1.ConfigMap
+	for i := range list.Items {
+		record := &list.Items[i]
+		if latest == nil || record.Data["deletedAt"] > latest.Data["deletedAt"] {
+			latest = record
+		}
+	}
+	return latest, nil
+}
+
+// forget removes the record of the database trash once it's undeleted
+func (t *trashBin) forget(trash string) error {
+	record := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: recordName(trash), Namespace: t.namespace}}
+	if err := t.client.Delete(context.TODO(), record); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Start implements manager.Runnable, it drops soft deleted databases after the retention
+func (t *trashBin) Start(stop <-chan struct{}) error {
+	wait.Until(t.purge, trashPurgeInterval, stop)
+	return nil
+}
+
+func (t *trashBin) purge() {
+	list := &corev1.ConfigMapList{}
+	opts := client.InNamespace(t.namespace).MatchingLabels(map[string]string{trashLabel: "true"})
+	if err := t.client.List(context.TODO(), opts, list); err != nil {
+		log.Error(err, "Unable to list soft deleted databases")
+		return
+	}
+
+	for i := range list.Items {
+		record := &list.Items[i]
+		purgeAfter, err := time.Parse(time.RFC3339, record.Data["purgeAfter"])
+		if err != nil {
+			log.Error(err, "Invalid soft deleted database record", "ConfigMap", record.Name)
+			continue
+		}
+		if time.Now().Before(purgeAfter) {
+			continue
+		}
+
+		trash := record.Data["database"]
+		srv, ok := servers[record.Data["server"]]
+		if !ok {
+			log.Info("Unable to purge soft deleted database, server is not configured", "Database", trash, "Server", record.Data["server"])
+			continue
+		}
+		exists, err := srv.postgresExists(trash)
+		if err != nil {
+			log.Error(err, "Unable to purge soft deleted database", "Database", trash, "Server", srv.Name)
+			continue
+		}
+		if exists {
+			if err := srv.postgresDelDB(trash); err != nil {
+				continue
+			}
+			databasesDropped.WithLabelValues(srv.Type, srv.Name).Inc()
+		}
+		log.Info("Purged soft deleted database", "Database", trash, "Server", srv.Name)
+
+		if err := t.client.Delete(context.TODO(), record); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Unable to remove soft deleted database record", "ConfigMap", record.Name)
+		}
+	}
+}
+
+// planUndelete pins the database to the server of its latest soft deleted copy, which is
+// renamed back instead of creating an empty database. refusal is why it can't be undeleted.
+func (r *ReconcileDatabase) planUndelete(db *dbv1beta1.Database) (refusal string, err error) {
+	if db.Spec.CloneFrom != nil {
+		return "a database can't be undeleted and cloned at once", nil
+	}
+	record, err := r.trash.find(db)
+	if err != nil {
+		return "", err
+	}
+	if record == nil {
+		return fmt.Sprintf("no soft deleted database %s of namespace %s found", db.Name, db.Namespace), nil
+	}
+
+	server := record.Data["server"]
+	if db.Spec.ServerRef != nil && db.Spec.ServerRef.Name != server {
+		return fmt.Sprintf("the soft deleted database is on server %s, not %s", server, db.Spec.ServerRef.Name), nil
+	}
+	db.Status.Server = server
+	db.Status.UndeletedFrom = record.Data["database"]
+	return "", nil
+}
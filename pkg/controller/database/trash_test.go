@@ -0,0 +1,93 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"db-operator/pkg/apis"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTrashName(t *testing.T) {
+	deleted := metav1.NewTime(time.Unix(1571234567, 0))
+	long := strings.Repeat("a", 63)
+
+	for _, c := range []struct {
+		name, want, record string
+	}{
+		{"app", "trash_app_1571234567", "trash-app-1571234567"},
+		{"my_app", "trash_my_app_1571234567", "trash-my-app-1571234567"},
+		{long, "trash_" + long[:maxTrashedName] + "_1571234567", "trash-" + long[:maxTrashedName] + "-1571234567"},
+	} {
+		db := &dbv1beta1.Database{ObjectMeta: metav1.ObjectMeta{Name: c.name, DeletionTimestamp: &deleted}}
+		got := trashName(db)
+		if got != c.want {
+			t.Errorf("trashName(%s) = %s, want %s", c.name, got, c.want)
+		}
+		if len(got) > 63 {
+			t.Errorf("trashName(%s) is %d characters long, postgres truncates it", c.name, len(got))
+		}
+		record := recordName(got)
+		if record != c.record {
+			t.Errorf("recordName(%s) = %s, want %s", got, record, c.record)
+		}
+		if errs := validation.IsDNS1123Subdomain(record); len(errs) > 0 {
+			t.Errorf("recordName(%s) is no valid ConfigMap name: %v", got, errs)
+		}
+	}
+
+	// Until the deletion timestamp is set the name changes with time
+	db := &dbv1beta1.Database{ObjectMeta: metav1.ObjectMeta{Name: "app"}}
+	if got := trashName(db); !strings.HasPrefix(got, "trash_app_") || got == "trash_app_1571234567" {
+		t.Errorf("trashName without deletion timestamp = %s", got)
+	}
+}
+
+func TestTrashFind(t *testing.T) {
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+	record := func(name, namespace, database, deletedAt string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "operator", Labels: map[string]string{
+				trashLabel:                    "true",
+				"db.clarizen.cloud/namespace": namespace,
+				"db.clarizen.cloud/name":      database,
+			}},
+			Data: map[string]string{"deletedAt": deletedAt},
+		}
+	}
+	bin := &trashBin{namespace: "operator", client: fake.NewFakeClientWithScheme(scheme.Scheme,
+		record("trash-app-1", "team", "app", "2019-10-16T12:00:00Z"),
+		record("trash-app-2", "team", "app", "2019-10-18T12:00:00Z"),
+		record("trash-app-3", "team", "app", "2019-10-17T12:00:00Z"),
+		record("trash-app-4", "other", "app", "2019-10-19T12:00:00Z"),
+	)}
+
+	for _, c := range []struct {
+		namespace, name, want string
+	}{
+		{"team", "app", "trash-app-2"},
+		{"other", "app", "trash-app-4"},
+		{"team", "web", ""},
+	} {
+		found, err := bin.find(&dbv1beta1.Database{ObjectMeta: metav1.ObjectMeta{Name: c.name, Namespace: c.namespace}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if found != nil {
+			got = found.Name
+		}
+		if got != c.want {
+			t.Errorf("find(%s/%s) = %q, want %q", c.namespace, c.name, got, c.want)
+		}
+	}
+}
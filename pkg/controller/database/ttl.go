@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// expire records when the database expires and deletes the Database object once it has,
+// expired is true if it was deleted
+func (r *ReconcileDatabase) expire(reqLogger logr.Logger, db *dbv1beta1.Database, ev eventer) (expired bool, err error) {
+	if db.Spec.TTL == nil {
+		db.Status.ExpiresAt = nil
+		return false, nil
+	}
+
+	expiresAt := db.CreationTimestamp.Add(db.Spec.TTL.Duration)
+	if extension, ok := db.GetAnnotations()[dbv1beta1.ExtendTTLAnnotation]; ok {
+		d, err := time.ParseDuration(extension)
+		if err != nil {
+			ev.warning(reasonExpired, "Ignoring the %s annotation: %s", dbv1beta1.ExtendTTLAnnotation, err)
+		} else {
+			expiresAt = expiresAt.Add(d)
+		}
+	}
+	t := metav1.NewTime(expiresAt)
+	db.Status.ExpiresAt = &t
+
+	if time.Now().Before(expiresAt) {
+		return false, nil
+	}
+	reqLogger.Info("Database expired, deleting it", "ExpiresAt", t.UTC().Format(time.RFC3339))
+	ev.normal(reasonExpired, "Database expired at %s, deleting it", t.UTC().Format(time.RFC3339))
+	return true, r.client.Delete(context.TODO(), db)
+}
+
+// requeueExpiry returns when the database has to be reconciled again to delete it
+func requeueExpiry(db *dbv1beta1.Database, result reconcile.Result) reconcile.Result {
+	if db.Status.ExpiresAt == nil {
+		return result
+	}
+	until := time.Until(db.Status.ExpiresAt.Time)
+	if result.RequeueAfter == 0 || until < result.RequeueAfter {
+		result.RequeueAfter = until
+	}
+	return result
+}
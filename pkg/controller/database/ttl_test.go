@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"db-operator/pkg/apis"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestExpire(t *testing.T) {
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-2 * time.Hour)
+
+	for _, c := range []struct {
+		name      string
+		ttl       time.Duration
+		extension string
+		expired   bool
+		lifetime  time.Duration
+		events    int
+	}{
+		{name: "no ttl"},
+		{name: "running", ttl: 3 * time.Hour, lifetime: 3 * time.Hour},
+		{name: "expired", ttl: time.Hour, expired: true, lifetime: time.Hour, events: 1},
+		{name: "extended", ttl: time.Hour, extension: "2h", lifetime: 3 * time.Hour},
+		{name: "extension expired", ttl: time.Hour, extension: "30m", expired: true, lifetime: 90 * time.Minute, events: 1},
+		{name: "invalid extension", ttl: 3 * time.Hour, extension: "2 days", lifetime: 3 * time.Hour, events: 1},
+	} {
+		db := &dbv1beta1.Database{ObjectMeta: metav1.ObjectMeta{
+			Name:              "app",
+			Namespace:         "team",
+			CreationTimestamp: metav1.NewTime(created),
+		}}
+		if c.ttl != 0 {
+			db.Spec.TTL = &metav1.Duration{Duration: c.ttl}
+		}
+		if c.extension != "" {
+			db.Annotations = map[string]string{dbv1beta1.ExtendTTLAnnotation: c.extension}
+		}
+		r := &ReconcileDatabase{client: fake.NewFakeClientWithScheme(scheme.Scheme, db.DeepCopy())}
+		recorder := record.NewFakeRecorder(10)
+
+		expired, err := r.expire(logf.Log, db, eventer{recorder, db})
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		if expired != c.expired {
+			t.Errorf("%s: expired is %t, want %t", c.name, expired, c.expired)
+		}
+		if c.ttl == 0 {
+			if db.Status.ExpiresAt != nil {
+				t.Errorf("%s: expires at %s", c.name, db.Status.ExpiresAt)
+			}
+		} else if want := created.Add(c.lifetime); db.Status.ExpiresAt == nil || !db.Status.ExpiresAt.Time.Equal(want) {
+			t.Errorf("%s: expires at %v, want %s", c.name, db.Status.ExpiresAt, want)
+		}
+		if len(recorder.Events) != c.events {
+			t.Errorf("%s: %d events recorded, want %d", c.name, len(recorder.Events), c.events)
+		}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "app", Namespace: "team"}, &dbv1beta1.Database{})
+		if deleted := errors.IsNotFound(err); deleted != c.expired {
+			t.Errorf("%s: deleted is %t, want %t", c.name, deleted, c.expired)
+		}
+	}
+}
+
+func TestRequeueExpiry(t *testing.T) {
+	for _, c := range []struct {
+		name         string
+		expiresIn    time.Duration
+		requeueAfter time.Duration
+		want         time.Duration
+	}{
+		{name: "no expiry", requeueAfter: time.Minute, want: time.Minute},
+		{name: "no requeue", expiresIn: time.Hour, want: time.Hour},
+		{name: "expiry first", expiresIn: time.Minute, requeueAfter: time.Hour, want: time.Minute},
+		{name: "requeue first", expiresIn: time.Hour, requeueAfter: time.Minute, want: time.Minute},
+	} {
+		db := &dbv1beta1.Database{}
+		if c.expiresIn != 0 {
+			expiresAt := metav1.NewTime(time.Now().Add(c.expiresIn))
+			db.Status.ExpiresAt = &expiresAt
+		}
+		got := requeueExpiry(db, reconcile.Result{RequeueAfter: c.requeueAfter}).RequeueAfter
+		// The time until expiry shrinks while the test runs
+		if got > c.want || got < c.want-time.Second {
+			t.Errorf("%s: requeued after %s, want %s", c.name, got, c.want)
+		}
+	}
+}
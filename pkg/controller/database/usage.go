@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var usageLabels = []string{"namespace", "name", "engine", "server"}
+
+var (
+	databaseSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_operator_database_size_bytes",
+		Help: "Disk space used by the managed database",
+	}, usageLabels)
+
+	databaseConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_operator_database_connections",
+		Help: "Open connections to the managed database",
+	}, usageLabels)
+
+	databaseXIDAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_operator_database_transaction_id_age",
+		Help: "Age of the oldest unfrozen transaction ID of the managed database",
+	}, usageLabels)
+)
+
+func init() {
+	metrics.Registry.MustRegister(databaseSize, databaseConnections, databaseXIDAge)
+	viper.SetDefault("usageInterval", "5m")
+}
+
+// usageCollector periodically measures every managed database on its server,
+// exports the values as gauges, mirrors them into the Database status and enforces size quotas
+type usageCollector struct {
+	client   client.Client
+	recorder record.EventRecorder
+	interval time.Duration
+
+	// exported holds the label values set in the last round, to remove gauges of deleted databases
+	exported map[[4]string]bool
+}
+
+func newUsageCollector(mgr manager.Manager) *usageCollector {
+	return &usageCollector{
+		client:   mgr.GetClient(),
+		recorder: mgr.GetRecorder("database-controller"),
+		interval: viper.GetDuration("usageInterval"),
+		exported: map[[4]string]bool{},
+	}
+}
+
+// Start implements manager.Runnable
+func (u *usageCollector) Start(stop <-chan struct{}) error {
+	wait.Until(u.collect, u.interval, stop)
+	return nil
+}
+
+func (u *usageCollector) collect() {
+	list := &dbv1beta1.DatabaseList{}
+	if err := u.client.List(context.TODO(), &client.ListOptions{}, list); err != nil {
+		log.Error(err, "Unable to list Databases for usage")
+		return
+	}
+
+	measured := map[string]map[string]dbv1beta1.DatabaseUsage{}
+	exported := map[[4]string]bool{}
+	for i := range list.Items {
+		db := &list.Items[i]
+		if db.Status.Phase != "Created" || db.Spec.Type != "postgres" {
+			continue
+		}
+		srv, err := serverFor(db)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := measured[srv.Name]; !ok {
+			usage, err := srv.postgresUsage()
+			if err != nil {
+				log.Error(err, "Unable to measure database usage", "Server", srv.Name)
+			}
+			measured[srv.Name] = usage
+		}
+		usage, ok := measured[srv.Name][db.Name]
+		if !ok {
+			continue
+		}
+
+		labels := [4]string{db.Namespace, db.Name, srv.Type, srv.Name}
+		databaseSize.WithLabelValues(labels[:]...).Set(float64(usage.Size.Value()))
+		databaseConnections.WithLabelValues(labels[:]...).Set(float64(usage.Connections))
+		databaseXIDAge.WithLabelValues(labels[:]...).Set(float64(usage.TransactionIDAge))
+		exported[labels] = true
+
+		if err := u.updateStatus(srv, db, usage); err != nil {
+			log.Error(err, "Unable to update Database usage", "Db.Namespace", db.Namespace, "Db.Name", db.Name)
+		}
+	}
+
+	for labels := range u.exported {
+		if !exported[labels] {
+			databaseSize.DeleteLabelValues(labels[:]...)
+			databaseConnections.DeleteLabelValues(labels[:]...)
+			databaseXIDAge.DeleteLabelValues(labels[:]...)
+		}
+	}
+	u.exported = exported
+}
+
+func (u *usageCollector) updateStatus(srv *server, db *dbv1beta1.Database, usage dbv1beta1.DatabaseUsage) error {
+	// Paused databases are not touched, the gauges are still exported
+	if db.GetAnnotations()[dbv1beta1.PausedAnnotation] == "true" {
+		return nil
+	}
+
+	db.Status.Usage = &usage
+	if err := enforceQuota(srv, db, usage, eventer{recorder: u.recorder, object: db}); err != nil {
+		return err
+	}
+	err := u.client.Status().Update(context.TODO(), db)
+	if errors.IsConflict(err) || errors.IsNotFound(err) {
+		// The next round will catch up
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,206 @@
+package databasebackup
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/backup"
+	"db-operator/pkg/controller/database"
+	"db-operator/pkg/jobs"
+	"db-operator/pkg/util"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_databasebackup")
+
+// backupFinalizer removes the dump before the DatabaseBackup is deleted
+const backupFinalizer = "finalizer.db.clarizen.cloud"
+
+// Reasons of the Events recorded on DatabaseBackups
+const (
+	reasonBackupStarted = "BackupStarted"
+	reasonBackedUp      = "BackedUp"
+	reasonBackupFailed  = "BackupFailed"
+)
+
+// Add creates a new DatabaseBackup Controller and adds it to the Manager
+func Add(mgr manager.Manager) error {
+	runner, err := jobs.NewRunner(mgr)
+	if err != nil {
+		return err
+	}
+	r := &ReconcileDatabaseBackup{
+		client:   mgr.GetClient(),
+		recorder: mgr.GetRecorder("databasebackup-controller"),
+		jobs:     runner,
+	}
+
+	c, err := controller.New("databasebackup-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	return c.Watch(&source.Kind{Type: &dbv1beta1.DatabaseBackup{}}, &handler.EnqueueRequestForObject{})
+}
+
+// blank assignment to verify that ReconcileDatabaseBackup implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileDatabaseBackup{}
+
+// ReconcileDatabaseBackup reconciles a DatabaseBackup object
+type ReconcileDatabaseBackup struct {
+	client   client.Client
+	recorder record.EventRecorder
+	jobs     *jobs.Runner
+}
+
+// Reconcile dumps the database into the backup storage once. Deleting the DatabaseBackup
+// deletes the dump.
+func (r *ReconcileDatabaseBackup) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+
+	instance := &dbv1beta1.DatabaseBackup{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if instance.GetDeletionTimestamp() != nil {
+		if !util.Contains(instance.GetFinalizers(), backupFinalizer) {
+			return reconcile.Result{}, nil
+		}
+		if instance.Status.Location != "" {
+			done, err := r.deleteDump(instance)
+			if err != nil || !done {
+				return reconcile.Result{RequeueAfter: jobs.PollInterval}, err
+			}
+		}
+		if err := r.jobs.Cleanup(jobs.Name("backup", instance)); err != nil {
+			return reconcile.Result{}, err
+		}
+
+		instance.SetFinalizers(util.Remove(instance.GetFinalizers(), backupFinalizer))
+		return reconcile.Result{}, r.client.Update(context.TODO(), instance)
+	}
+
+	if instance.Status.Phase == dbv1beta1.BackupCompleted || instance.Status.Phase == dbv1beta1.BackupFailed {
+		return reconcile.Result{}, nil
+	}
+
+	if !util.Contains(instance.GetFinalizers(), backupFinalizer) {
+		instance.SetFinalizers(append(instance.GetFinalizers(), backupFinalizer))
+		if err := r.client.Update(context.TODO(), instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	db := &dbv1beta1.Database{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: instance.Spec.DatabaseName}, db)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, r.fail(instance, fmt.Errorf("database %s not found", instance.Spec.DatabaseName))
+		}
+		return reconcile.Result{}, err
+	}
+	if db.Status.Phase != "Created" {
+		reqLogger.Info("Waiting for the database to be created", "Database", db.Name)
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	server, dsn, err := database.DSN(db)
+	if err != nil {
+		return reconcile.Result{}, r.fail(instance, err)
+	}
+	storage, err := backup.StorageFor(instance.Spec.Storage)
+	if err != nil {
+		return reconcile.Result{}, r.fail(instance, err)
+	}
+
+	key := backup.Key(instance)
+	if instance.Status.Phase == "" {
+		now := metav1.Now()
+		instance.Status.Phase = dbv1beta1.BackupRunning
+		instance.Status.StartTime = &now
+		instance.Status.Server = server
+		instance.Status.Storage = storage.Name
+		instance.Status.Location = storage.Location(key)
+		if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+			return reconcile.Result{}, err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonBackupStarted, "Backing up database %s to %s", db.Name, instance.Status.Location)
+	}
+
+	name := jobs.Name("backup", instance)
+	state, err := r.jobs.Run(name, instance, storage.DumpJob(dsn, key))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	switch state {
+	case jobs.Running:
+		return reconcile.Result{RequeueAfter: jobs.PollInterval}, nil
+	case jobs.Failed:
+		// The Job is kept for its logs until the DatabaseBackup is deleted
+		return reconcile.Result{}, r.fail(instance, fmt.Errorf("backup job %s/%s failed", r.jobs.Namespace, name))
+	}
+
+	size, err := r.jobs.Result(name)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if bytes, err := strconv.ParseInt(size, 10, 64); err == nil {
+		instance.Status.Size = resource.NewQuantity(bytes, resource.BinarySI)
+	}
+	now := metav1.Now()
+	instance.Status.Phase = dbv1beta1.BackupCompleted
+	instance.Status.CompletionTime = &now
+	instance.Status.Duration = &metav1.Duration{Duration: now.Sub(instance.Status.StartTime.Time).Round(time.Second)}
+	if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+		return reconcile.Result{}, err
+	}
+	r.recorder.Eventf(instance, corev1.EventTypeNormal, reasonBackedUp, "Database %s backed up to %s", db.Name, instance.Status.Location)
+
+	return reconcile.Result{}, r.jobs.Cleanup(name)
+}
+
+// deleteDump removes the dump from the storage, done is false while the Job runs
+func (r *ReconcileDatabaseBackup) deleteDump(b *dbv1beta1.DatabaseBackup) (done bool, err error) {
+	storage, err := backup.StorageFor(b.Status.Storage)
+	if err != nil {
+		r.recorder.Eventf(b, corev1.EventTypeWarning, reasonBackupFailed, "Unable to delete %s: %s", b.Status.Location, err)
+		return true, nil
+	}
+
+	name := jobs.Name("rm-backup", b)
+	state, err := r.jobs.Run(name, b, storage.DeleteJob(backup.Key(b)))
+	if err != nil || state == jobs.Running {
+		return false, err
+	}
+	if state == jobs.Failed {
+		r.recorder.Eventf(b, corev1.EventTypeWarning, reasonBackupFailed, "Unable to delete %s, it has to be removed manually", b.Status.Location)
+	}
+	return true, r.jobs.Cleanup(name)
+}
+
+func (r *ReconcileDatabaseBackup) fail(b *dbv1beta1.DatabaseBackup, err error) error {
+	r.recorder.Eventf(b, corev1.EventTypeWarning, reasonBackupFailed, "%s", err.Error())
+	now := metav1.Now()
+	b.Status.Phase = dbv1beta1.BackupFailed
+	b.Status.Error = err.Error()
+	b.Status.CompletionTime = &now
+	return r.client.Status().Update(context.TODO(), b)
+}
@@ -0,0 +1,150 @@
+package databasebackup
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// defaultBackupsKept is how many DatabaseBackups a schedule keeps without spec.keep
+const defaultBackupsKept = 7
+
+// AddSchedule creates a new DatabaseBackupSchedule Controller and adds it to the Manager
+func AddSchedule(mgr manager.Manager) error {
+	r := &ReconcileDatabaseBackupSchedule{client: mgr.GetClient()}
+
+	c, err := controller.New("databasebackupschedule-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &dbv1beta1.DatabaseBackupSchedule{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Finished backups may make older ones obsolete
+	return c.Watch(&source.Kind{Type: &dbv1beta1.DatabaseBackup{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			schedule, ok := o.Meta.GetLabels()[dbv1beta1.BackupScheduleLabel]
+			if !ok {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: o.Meta.GetNamespace(), Name: schedule}}}
+		}),
+	})
+}
+
+// blank assignment to verify that ReconcileDatabaseBackupSchedule implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileDatabaseBackupSchedule{}
+
+// ReconcileDatabaseBackupSchedule reconciles a DatabaseBackupSchedule object
+type ReconcileDatabaseBackupSchedule struct {
+	client client.Client
+}
+
+// Reconcile creates a DatabaseBackup whenever the schedule is due and deletes the backups
+// beyond spec.keep. Backups outlive the schedule, they aren't owned by it.
+func (r *ReconcileDatabaseBackupSchedule) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	instance := &dbv1beta1.DatabaseBackupSchedule{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	schedule, err := cron.ParseStandard(instance.Spec.Schedule)
+	if err != nil {
+		instance.Status.Error = fmt.Sprintf("invalid schedule: %s", err)
+		return reconcile.Result{}, r.client.Status().Update(context.TODO(), instance)
+	}
+
+	last := instance.GetCreationTimestamp()
+	if instance.Status.LastScheduleTime != nil {
+		last = *instance.Status.LastScheduleTime
+	}
+	now := time.Now()
+	next := schedule.Next(last.Time)
+
+	if !instance.Spec.Suspend && !now.Before(next) {
+		backup := &dbv1beta1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", instance.Name, now.Unix()),
+				Namespace: instance.Namespace,
+				Labels:    map[string]string{dbv1beta1.BackupScheduleLabel: instance.Name},
+			},
+			Spec: dbv1beta1.DatabaseBackupSpec{
+				DatabaseName: instance.Spec.DatabaseName,
+				Storage:      instance.Spec.Storage,
+			},
+		}
+		if err := r.client.Create(context.TODO(), backup); err != nil && !errors.IsAlreadyExists(err) {
+			return reconcile.Result{}, err
+		}
+
+		scheduled := metav1.NewTime(now)
+		instance.Status.LastScheduleTime = &scheduled
+		instance.Status.LastBackup = backup.Name
+		instance.Status.Error = ""
+		if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+			return reconcile.Result{}, err
+		}
+		next = schedule.Next(now)
+	}
+
+	if err := r.prune(instance); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{RequeueAfter: next.Sub(now)}, nil
+}
+
+// prune deletes finished backups of the schedule beyond spec.keep, oldest first
+func (r *ReconcileDatabaseBackupSchedule) prune(s *dbv1beta1.DatabaseBackupSchedule) error {
+	keep := defaultBackupsKept
+	if s.Spec.Keep != nil {
+		keep = int(*s.Spec.Keep)
+	}
+
+	list := &dbv1beta1.DatabaseBackupList{}
+	opts := client.InNamespace(s.Namespace).MatchingLabels(map[string]string{dbv1beta1.BackupScheduleLabel: s.Name})
+	if err := r.client.List(context.TODO(), opts, list); err != nil {
+		return err
+	}
+
+	var backups []dbv1beta1.DatabaseBackup
+	for _, b := range list.Items {
+		if b.GetDeletionTimestamp() == nil {
+			backups = append(backups, b)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[j].CreationTimestamp.Before(&backups[i].CreationTimestamp)
+	})
+
+	for i := keep; i < len(backups); i++ {
+		b := &backups[i]
+		if b.Status.Phase != dbv1beta1.BackupCompleted && b.Status.Phase != dbv1beta1.BackupFailed {
+			continue
+		}
+		if err := r.client.Delete(context.TODO(), b); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package databasequota
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/quota"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_databasequota")
+
+// Add creates a new DatabaseQuota Controller which keeps the usage in the quota status up to date
+func Add(mgr manager.Manager) error {
+	return add(mgr, &ReconcileDatabaseQuota{client: mgr.GetClient()})
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("databasequota-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &dbv1beta1.DatabaseQuota{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Any change to a Database may change the usage of the quotas in its namespace
+	return c.Watch(&source.Kind{Type: &dbv1beta1.Database{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			list := &dbv1beta1.DatabaseQuotaList{}
+			if err := mgr.GetClient().List(context.TODO(), &client.ListOptions{Namespace: o.Meta.GetNamespace()}, list); err != nil {
+				log.Error(err, "Unable to list DatabaseQuotas", "Namespace", o.Meta.GetNamespace())
+				return nil
+			}
+			var requests []reconcile.Request
+			for _, q := range list.Items {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: q.Namespace, Name: q.Name}})
+			}
+			return requests
+		}),
+	})
+}
+
+// blank assignment to verify that ReconcileDatabaseQuota implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileDatabaseQuota{}
+
+// ReconcileDatabaseQuota reconciles a DatabaseQuota object
+type ReconcileDatabaseQuota struct {
+	client client.Client
+}
+
+// Reconcile updates the usage in the DatabaseQuota status. Limits are enforced by
+// the Database controller and the validating webhook.
+func (r *ReconcileDatabaseQuota) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	instance := &dbv1beta1.DatabaseQuota{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	usage, err := quota.Usage(r.client, instance.Namespace)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if equality.Semantic.DeepEqual(usage, instance.Status) {
+		return reconcile.Result{}, nil
+	}
+
+	instance.Status = usage
+	return reconcile.Result{}, r.client.Status().Update(context.TODO(), instance)
+}
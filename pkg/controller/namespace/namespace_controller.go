@@ -0,0 +1,62 @@
+package namespace
+
+import (
+	"context"
+	"db-operator/pkg/namespace"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_namespace")
+
+// Add creates a new Namespace Controller which keeps the watched namespaces
+// matching NAMESPACE_SELECTOR up to date. Nothing is added without a selector.
+func Add(mgr manager.Manager) error {
+	if !namespace.Watched.HasSelector() {
+		return nil
+	}
+	return add(mgr, &ReconcileNamespace{client: mgr.GetClient(), filter: namespace.Watched})
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("namespace-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	return c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestForObject{})
+}
+
+// blank assignment to verify that ReconcileNamespace implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileNamespace{}
+
+// ReconcileNamespace feeds Namespace labels into the namespace filter
+type ReconcileNamespace struct {
+	client client.Client
+	filter *namespace.Filter
+}
+
+// Reconcile updates the filter with the current labels of the Namespace
+func (r *ReconcileNamespace) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ns := &corev1.Namespace{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, ns)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			r.filter.Forget(request.Name)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.filter.Observe(ns)
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,19 @@
+// Package conversion defines the hub and spoke model used to convert
+// between the served versions of a custom resource.
+package conversion
+
+import "k8s.io/apimachinery/pkg/runtime"
+
+// Hub is the version every other version of the same kind converts to and from.
+// There is exactly one Hub per kind, it is usually the storage version.
+type Hub interface {
+	runtime.Object
+	Hub()
+}
+
+// Convertible is a spoke version which knows how to convert itself to and from the Hub.
+type Convertible interface {
+	runtime.Object
+	ConvertTo(dst Hub) error
+	ConvertFrom(src Hub) error
+}
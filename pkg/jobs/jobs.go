@@ -0,0 +1,203 @@
+// Package jobs runs pg_dump, pg_restore and storage tools in Jobs for the controllers.
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"github.com/spf13/viper"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("jobs")
+
+// PollInterval is how often running Jobs are checked
+const PollInterval = 15 * time.Second
+
+func init() {
+	viper.SetDefault("toolsImage", "postgres:12-alpine")
+}
+
+// State is the outcome of a Job run by the Runner
+type State int
+
+const (
+	// Running Jobs are checked again after PollInterval
+	Running State = iota
+	// Succeeded Jobs are kept until they're cleaned up
+	Succeeded
+	// Failed Jobs are kept for their logs until they're cleaned up
+	Failed
+)
+
+// Runner runs pg_dump and pg_restore in Jobs. The Jobs carry admin credentials of the
+// servers, so they run in the operator namespace and never in the namespace of the Database.
+type Runner struct {
+	// Client talks to the apiserver directly, the operator namespace may not be watched
+	Client    client.Client
+	Namespace string
+	image     string
+}
+
+// Spec describes what a Job runs. Env is stored in a Secret next to the Job.
+type Spec struct {
+	// Steps run one after another, each one in its own container
+	Steps   []Step
+	Env     map[string]string
+	Volumes []corev1.Volume
+	Mounts  []corev1.VolumeMount
+}
+
+// Step is a shell script run in Image, the tools image if it's empty.
+// The script may write its result to /dev/termination-log.
+type Step struct {
+	Image  string
+	Script string
+}
+
+// NewRunner creates a Runner for the Jobs in the jobNamespace of the operator config,
+// the operator namespace by default
+func NewRunner(mgr manager.Manager) (*Runner, error) {
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := viper.GetString("jobNamespace")
+	if namespace == "" {
+		if namespace, err = k8sutil.GetOperatorNamespace(); err != nil {
+			log.Info("Unable to get operator namespace, running jobs in default", "error", err.Error())
+			namespace = "default"
+		}
+	}
+
+	return &Runner{Client: c, Namespace: namespace, image: viper.GetString("toolsImage")}, nil
+}
+
+// Name builds a unique name for a Job of obj, within the length limit of labels
+func Name(purpose string, obj metav1.Object) string {
+	sum := sha256.Sum256([]byte(obj.GetNamespace() + "/" + obj.GetName()))
+	name := fmt.Sprintf("%s-%s", purpose, obj.GetName())
+	if len(name) > 52 {
+		name = name[:52]
+	}
+	return fmt.Sprintf("%s-%x", name, sum[:5])
+}
+
+// Run starts the Job for obj unless it exists already and reports its state
+func (j *Runner) Run(name string, obj metav1.Object, spec Spec) (State, error) {
+	job := &batchv1.Job{}
+	err := j.Client.Get(context.TODO(), types.NamespacedName{Namespace: j.Namespace, Name: name}, job)
+	if err == nil {
+		switch {
+		case job.Status.Succeeded > 0:
+			return Succeeded, nil
+		case job.Spec.BackoffLimit != nil && job.Status.Failed > *job.Spec.BackoffLimit:
+			return Failed, nil
+		default:
+			return Running, nil
+		}
+	}
+	if !errors.IsNotFound(err) {
+		return Running, err
+	}
+
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "db-operator",
+		"db.clarizen.cloud/namespace":  obj.GetNamespace(),
+		"db.clarizen.cloud/name":       obj.GetName(),
+	}
+
+	var containers []corev1.Container
+	for i, step := range spec.Steps {
+		image := step.Image
+		if image == "" {
+			image = j.image
+		}
+		containers = append(containers, corev1.Container{
+			Name:         fmt.Sprintf("step-%d", i),
+			Image:        image,
+			Command:      []string{"/bin/sh", "-c", "set -e -o pipefail\n" + step.Script},
+			EnvFrom:      []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}}}},
+			VolumeMounts: spec.Mounts,
+		})
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: j.Namespace, Labels: labels},
+		StringData: spec.Env,
+	}
+	if err := j.Client.Create(context.TODO(), secret); err != nil && !errors.IsAlreadyExists(err) {
+		return Running, err
+	}
+
+	backoffLimit := int32(2)
+	job = &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: j.Namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:  corev1.RestartPolicyNever,
+					Volumes:        spec.Volumes,
+					InitContainers: containers[:len(containers)-1],
+					Containers:     containers[len(containers)-1:],
+				},
+			},
+		},
+	}
+	if err := j.Client.Create(context.TODO(), job); err != nil && !errors.IsAlreadyExists(err) {
+		return Running, err
+	}
+	log.Info("Job started", "Job", name, "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+
+	return Running, nil
+}
+
+// Result returns the termination message of the last step of a succeeded Job
+func (j *Runner) Result(name string) (string, error) {
+	pods := &corev1.PodList{}
+	opts := client.InNamespace(j.Namespace).MatchingLabels(map[string]string{"job-name": name})
+	if err := j.Client.List(context.TODO(), opts, pods); err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, c := range pod.Status.ContainerStatuses {
+			if c.State.Terminated != nil {
+				return strings.TrimSpace(c.State.Terminated.Message), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no succeeded pod found for job %s", name)
+}
+
+// Cleanup removes the Job, its pods and its Secret
+func (j *Runner) Cleanup(name string) error {
+	background := metav1.DeletePropagationBackground
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: j.Namespace}}
+	err := j.Client.Delete(context.TODO(), job, client.PropagationPolicy(background))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: j.Namespace}}
+	if err := j.Client.Delete(context.TODO(), secret); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
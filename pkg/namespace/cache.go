@@ -0,0 +1,108 @@
+package namespace
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// NewCache returns a manager.NewCacheFunc which hides objects in namespaces
+// not watched by f, both from reads and from informer event handlers.
+func NewCache(f *Filter) manager.NewCacheFunc {
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		c, err := cache.New(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &filteredCache{Cache: c, filter: f}, nil
+	}
+}
+
+type filteredCache struct {
+	cache.Cache
+	filter *Filter
+}
+
+func (c *filteredCache) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if !c.filter.IsWatched(key.Namespace) {
+		return errors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	return c.Cache.Get(ctx, key, obj)
+}
+
+func (c *filteredCache) List(ctx context.Context, opts *client.ListOptions, list runtime.Object) error {
+	if opts != nil && opts.Namespace != "" && !c.filter.IsWatched(opts.Namespace) {
+		return meta.SetList(list, nil)
+	}
+	if err := c.Cache.List(ctx, opts, list); err != nil {
+		return err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	filtered := items[:0]
+	for _, item := range items {
+		if c.filter.admits(item) {
+			filtered = append(filtered, item)
+		}
+	}
+	return meta.SetList(list, filtered)
+}
+
+func (c *filteredCache) GetInformer(obj runtime.Object) (toolscache.SharedIndexInformer, error) {
+	i, err := c.Cache.GetInformer(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &filteredInformer{SharedIndexInformer: i, filter: c.filter}, nil
+}
+
+func (c *filteredCache) GetInformerForKind(gvk schema.GroupVersionKind) (toolscache.SharedIndexInformer, error) {
+	i, err := c.Cache.GetInformerForKind(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return &filteredInformer{SharedIndexInformer: i, filter: c.filter}, nil
+}
+
+// filteredInformer only passes events for objects in watched namespaces to its handlers
+type filteredInformer struct {
+	toolscache.SharedIndexInformer
+	filter *Filter
+}
+
+func (i *filteredInformer) AddEventHandler(handler toolscache.ResourceEventHandler) {
+	i.SharedIndexInformer.AddEventHandler(toolscache.FilteringResourceEventHandler{
+		FilterFunc: i.filter.admits,
+		Handler:    handler,
+	})
+}
+
+func (i *filteredInformer) AddEventHandlerWithResyncPeriod(handler toolscache.ResourceEventHandler, resyncPeriod time.Duration) {
+	i.SharedIndexInformer.AddEventHandlerWithResyncPeriod(toolscache.FilteringResourceEventHandler{
+		FilterFunc: i.filter.admits,
+		Handler:    handler,
+	}, resyncPeriod)
+}
+
+func (f *Filter) admits(obj interface{}) bool {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	return f.IsWatched(accessor.GetNamespace())
+}
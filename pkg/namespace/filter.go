@@ -0,0 +1,127 @@
+// Package namespace decides which namespaces the operator manages.
+package namespace
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("namespace_filter")
+
+// Watched is the filter configured from the environment:
+// NAMESPACES is a comma separated list of namespaces to manage, "*" manages every namespace
+// seen by the manager cache. NAMESPACE_SELECTOR is a label selector for additional namespaces.
+// If neither is set no namespace is managed.
+var Watched *Filter
+
+func init() {
+	var err error
+	Watched, err = NewFilter(os.Getenv("NAMESPACES"), os.Getenv("NAMESPACE_SELECTOR"))
+	if err != nil {
+		log.Error(err, "Invalid NAMESPACE_SELECTOR")
+		os.Exit(1)
+	}
+}
+
+// Filter keeps track of the namespaces the operator manages.
+// Namespaces matching the selector are learned from Namespace events,
+// so labelling a namespace makes it managed without a restart.
+type Filter struct {
+	all      bool
+	static   map[string]bool
+	selector labels.Selector
+
+	mu       sync.RWMutex
+	matching map[string]bool
+	onWatch  []func(ns string)
+}
+
+// NewFilter creates a Filter from a comma separated namespace list and a label selector
+func NewFilter(namespaces, selector string) (*Filter, error) {
+	f := &Filter{static: map[string]bool{}, matching: map[string]bool{}}
+	for _, ns := range strings.Split(namespaces, ",") {
+		switch ns = strings.TrimSpace(ns); ns {
+		case "":
+		case "*":
+			f.all = true
+		default:
+			f.static[ns] = true
+		}
+	}
+	if selector != "" {
+		s, err := labels.Parse(selector)
+		if err != nil {
+			return nil, err
+		}
+		f.selector = s
+	}
+	return f, nil
+}
+
+// HasSelector reports if namespaces are matched by labels, which needs Namespaces to be watched
+func (f *Filter) HasSelector() bool {
+	return f.selector != nil
+}
+
+// IsWatched reports if objects in ns are managed by the operator.
+// Cluster scoped objects (empty ns) always pass.
+func (f *Filter) IsWatched(ns string) bool {
+	if ns == "" || f.all {
+		return true
+	}
+	if f.static[ns] {
+		return true
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.matching[ns]
+}
+
+// OnWatch registers fn to be called with every namespace which starts being watched
+// after the operator started, so objects in it can be picked up.
+func (f *Filter) OnWatch(fn func(ns string)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onWatch = append(f.onWatch, fn)
+}
+
+// Observe updates the filter with the current labels of ns
+func (f *Filter) Observe(ns *corev1.Namespace) {
+	if f.selector == nil {
+		return
+	}
+	matches := f.selector.Matches(labels.Set(ns.GetLabels())) && ns.Status.Phase != corev1.NamespaceTerminating
+
+	f.mu.Lock()
+	wasWatched := f.static[ns.Name] || f.matching[ns.Name]
+	if matches {
+		f.matching[ns.Name] = true
+	} else {
+		delete(f.matching, ns.Name)
+	}
+	callbacks := f.onWatch
+	f.mu.Unlock()
+
+	if matches && !wasWatched {
+		log.Info("Namespace is now watched", "Namespace", ns.Name)
+		for _, fn := range callbacks {
+			fn(ns.Name)
+		}
+	}
+	if !matches && wasWatched && !f.static[ns.Name] {
+		log.Info("Namespace is no longer watched", "Namespace", ns.Name)
+	}
+}
+
+// Forget removes a deleted namespace
+func (f *Filter) Forget(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.matching, name)
+}
@@ -0,0 +1,125 @@
+package namespace
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+func newFilter(t *testing.T, namespaces, selector string) *Filter {
+	f, err := NewFilter(namespaces, selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func ns(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestStaticNamespaces(t *testing.T) {
+	for _, c := range []struct {
+		namespaces string
+		watched    map[string]bool
+	}{
+		// Nothing is managed unless configured
+		{"", map[string]bool{"default": false, "team": false, "": true}},
+		{" , ", map[string]bool{"default": false, "": true}},
+		{"team, other", map[string]bool{"team": true, "other": true, "default": false, "": true}},
+		{"*", map[string]bool{"team": true, "default": true, "": true}},
+		{"team,*", map[string]bool{"team": true, "default": true}},
+	} {
+		f := newFilter(t, c.namespaces, "")
+		for name, want := range c.watched {
+			if got := f.IsWatched(name); got != want {
+				t.Errorf("NAMESPACES=%q: IsWatched(%q) = %t, want %t", c.namespaces, name, got, want)
+			}
+		}
+		if f.HasSelector() {
+			t.Errorf("NAMESPACES=%q: no selector was given", c.namespaces)
+		}
+	}
+}
+
+func TestInvalidSelector(t *testing.T) {
+	if _, err := NewFilter("", "a in (b"); err == nil {
+		t.Error("invalid selector was accepted")
+	}
+}
+
+func TestSelector(t *testing.T) {
+	f := newFilter(t, "static", "db.clarizen.cloud/managed=true")
+	if !f.HasSelector() {
+		t.Fatal("selector is missing")
+	}
+	var started []string
+	f.OnWatch(func(ns string) { started = append(started, ns) })
+
+	managed := map[string]string{"db.clarizen.cloud/managed": "true"}
+	if f.IsWatched("team") {
+		t.Error("team is watched before it was observed")
+	}
+
+	f.Observe(ns("team", managed))
+	f.Observe(ns("team", managed))
+	f.Observe(ns("other", map[string]string{"db.clarizen.cloud/managed": "false"}))
+	f.Observe(ns("static", managed))
+	if !f.IsWatched("team") || f.IsWatched("other") || !f.IsWatched("static") {
+		t.Errorf("watched: team %t, other %t, static %t", f.IsWatched("team"), f.IsWatched("other"), f.IsWatched("static"))
+	}
+	// Callbacks only run for namespaces which weren't watched already
+	if len(started) != 1 || started[0] != "team" {
+		t.Errorf("OnWatch was called for %v, want [team]", started)
+	}
+
+	// Removing the label or deleting the namespace stops watching it
+	f.Observe(ns("team", nil))
+	if f.IsWatched("team") {
+		t.Error("team is still watched without the label")
+	}
+	terminating := ns("team", managed)
+	terminating.Status.Phase = corev1.NamespaceTerminating
+	f.Observe(ns("team", managed))
+	f.Observe(terminating)
+	if f.IsWatched("team") {
+		t.Error("terminating namespace is still watched")
+	}
+	f.Observe(ns("team", managed))
+	f.Forget("team")
+	if f.IsWatched("team") {
+		t.Error("deleted namespace is still watched")
+	}
+	// Static namespaces stay watched whatever their labels
+	f.Observe(ns("static", nil))
+	if !f.IsWatched("static") {
+		t.Error("static namespace is no longer watched")
+	}
+}
+
+func TestObserveWithoutSelector(t *testing.T) {
+	f := newFilter(t, "", "")
+	f.OnWatch(func(ns string) { t.Errorf("OnWatch was called for %s", ns) })
+	f.Observe(ns("team", map[string]string{"db.clarizen.cloud/managed": "true"}))
+	if f.IsWatched("team") {
+		t.Error("namespaces are only learned with a selector")
+	}
+}
+
+func TestAdmits(t *testing.T) {
+	f := newFilter(t, "team", "")
+	inTeam := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "team"}}
+	inOther := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "other"}}
+
+	if !f.admits(inTeam) || f.admits(inOther) {
+		t.Errorf("admits: team %t, other %t", f.admits(inTeam), f.admits(inOther))
+	}
+	if !f.admits(toolscache.DeletedFinalStateUnknown{Key: "team/a", Obj: inTeam}) {
+		t.Error("tombstone of a watched object was refused")
+	}
+	if f.admits("not an object") {
+		t.Error("non-object was admitted")
+	}
+}
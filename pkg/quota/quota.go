@@ -0,0 +1,111 @@
+package quota
+
+import (
+	"context"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/util"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ExceededError is returned when a Database doesn't fit into a DatabaseQuota of its namespace
+type ExceededError struct {
+	Quota  string
+	Reason string
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("exceeded DatabaseQuota %s: %s", e.Quota, e.Reason)
+}
+
+// IsExceeded reports if err is an ExceededError
+func IsExceeded(err error) bool {
+	_, ok := err.(*ExceededError)
+	return ok
+}
+
+// Admit checks db, which is going to be placed on server, against every DatabaseQuota of its namespace.
+// The server check is skipped if server is empty, i.e. not known yet.
+// old is the Database before an update and nil on create, updates are only refused when they
+// raise the storage quota or move the database to a server which isn't allowed.
+func Admit(c client.Client, db, old *dbv1beta1.Database, server string) error {
+	quotas := &dbv1beta1.DatabaseQuotaList{}
+	if err := c.List(context.TODO(), &client.ListOptions{Namespace: db.Namespace}, quotas); err != nil {
+		return err
+	}
+	if len(quotas.Items) == 0 {
+		return nil
+	}
+
+	// Usage of everything else in the namespace, db itself is added below
+	others, err := Usage(c, db.Namespace, db.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, q := range quotas.Items {
+		if old == nil && q.Spec.MaxDatabases != nil && others.Databases+1 > *q.Spec.MaxDatabases {
+			return &ExceededError{Quota: q.Name, Reason: fmt.Sprintf("at most %d Databases are allowed", *q.Spec.MaxDatabases)}
+		}
+
+		if q.Spec.MaxStorage != nil && (old == nil || sizeOf(db) > sizeOf(old)) {
+			if db.Spec.Quota == nil {
+				return &ExceededError{Quota: q.Name, Reason: "spec.quota.maxSize is required"}
+			}
+			total := others.Storage.DeepCopy()
+			total.Add(db.Spec.Quota.MaxSize)
+			if total.Cmp(*q.Spec.MaxStorage) > 0 {
+				return &ExceededError{Quota: q.Name, Reason: fmt.Sprintf("total storage %s is above %s", total.String(), q.Spec.MaxStorage.String())}
+			}
+		}
+
+		if server != "" && len(q.Spec.AllowedServers) > 0 && !util.Contains(q.Spec.AllowedServers, server) {
+			return &ExceededError{Quota: q.Name, Reason: fmt.Sprintf("server %s is not allowed", server)}
+		}
+	}
+
+	return nil
+}
+
+// Usage sums up the Databases in namespace, except the ones named in exclude.
+// Databases count once they are provisioned and until they are being deleted.
+func Usage(c client.Client, namespace string, exclude ...string) (dbv1beta1.DatabaseQuotaStatus, error) {
+	status := dbv1beta1.DatabaseQuotaStatus{
+		Storage:         *resource.NewQuantity(0, resource.BinarySI),
+		MeasuredStorage: *resource.NewQuantity(0, resource.BinarySI),
+	}
+
+	list := &dbv1beta1.DatabaseList{}
+	if err := c.List(context.TODO(), &client.ListOptions{Namespace: namespace}, list); err != nil {
+		return status, err
+	}
+
+	for _, db := range list.Items {
+		if db.Status.Phase == "" || db.GetDeletionTimestamp() != nil || util.Contains(exclude, db.Name) {
+			continue
+		}
+		status.Databases++
+		if db.Spec.Quota != nil {
+			status.Storage.Add(db.Spec.Quota.MaxSize)
+		}
+		if db.Status.Usage != nil {
+			status.MeasuredStorage.Add(db.Status.Usage.Size)
+		}
+		if db.Status.Server != "" && !util.Contains(status.Servers, db.Status.Server) {
+			status.Servers = append(status.Servers, db.Status.Server)
+		}
+	}
+	sort.Strings(status.Servers)
+
+	return status, nil
+}
+
+func sizeOf(db *dbv1beta1.Database) int64 {
+	if db.Spec.Quota == nil {
+		return 0
+	}
+	return db.Spec.Quota.MaxSize.Value()
+}
@@ -0,0 +1,116 @@
+package quota
+
+import (
+	"testing"
+
+	"db-operator/pkg/apis"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func database(name, maxSize string) *dbv1beta1.Database {
+	db := &dbv1beta1.Database{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team"},
+		Status:     dbv1beta1.DatabaseStatus{Phase: "Created", Server: "default"},
+	}
+	if maxSize != "" {
+		db.Spec.Quota = &dbv1beta1.DatabaseSizeQuota{MaxSize: resource.MustParse(maxSize)}
+	}
+	return db
+}
+
+func TestAdmit(t *testing.T) {
+	// The fake client decodes lists with the client-go scheme
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+	maxDatabases := int32(2)
+	maxStorage := resource.MustParse("10Gi")
+	quota := &dbv1beta1.DatabaseQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "team"},
+		Spec: dbv1beta1.DatabaseQuotaSpec{
+			MaxDatabases:   &maxDatabases,
+			MaxStorage:     &maxStorage,
+			AllowedServers: []string{"default", "reporting"},
+		},
+	}
+	deleting := database("deleting", "8Gi")
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	pending := database("pending", "8Gi")
+	pending.Status = dbv1beta1.DatabaseStatus{}
+	objs := []runtime.Object{quota, database("app", "4Gi"), deleting, pending}
+
+	for _, c := range []struct {
+		name     string
+		db, old  *dbv1beta1.Database
+		server   string
+		objs     []runtime.Object
+		exceeded bool
+	}{
+		{name: "create within quota", db: database("new", "6Gi"), server: "default"},
+		{name: "create without maxSize", db: database("new", ""), exceeded: true},
+		{name: "create above maxStorage", db: database("new", "7Gi"), exceeded: true},
+		{name: "create above maxDatabases", db: database("new", "1Gi"), objs: []runtime.Object{database("other", "")}, exceeded: true},
+		{name: "create on a server which isn't allowed", db: database("new", "1Gi"), server: "other", exceeded: true},
+		{name: "create before the server is known", db: database("new", "1Gi")},
+		{name: "update at maxDatabases", db: database("app", "4Gi"), old: database("app", "4Gi"), objs: []runtime.Object{database("other", "")}},
+		{name: "update without growth above maxStorage", db: database("app", "4Gi"), old: database("app", "4Gi"), objs: []runtime.Object{database("big", "20Gi")}},
+		{name: "update shrinking above maxStorage", db: database("app", "2Gi"), old: database("app", "4Gi"), objs: []runtime.Object{database("big", "20Gi")}},
+		{name: "update growing within maxStorage", db: database("app", "10Gi"), old: database("app", "4Gi")},
+		{name: "update growing above maxStorage", db: database("app", "11Gi"), old: database("app", "4Gi"), exceeded: true},
+		{name: "update removing maxSize", db: database("app", ""), old: database("app", "4Gi")},
+		{name: "update adding maxSize", db: database("app", "4Gi"), old: database("app", "")},
+		{name: "update moving to an allowed server", db: database("app", "4Gi"), old: database("app", "4Gi"), server: "reporting"},
+		{name: "update moving to a server which isn't allowed", db: database("app", "4Gi"), old: database("app", "4Gi"), server: "other", exceeded: true},
+	} {
+		cl := fake.NewFakeClientWithScheme(scheme.Scheme, append(objs, c.objs...)...)
+		err := Admit(cl, c.db, c.old, c.server)
+		if err != nil && !IsExceeded(err) {
+			t.Errorf("%s: %s", c.name, err)
+		} else if (err != nil) != c.exceeded {
+			t.Errorf("%s: exceeded is %t, want %t: %v", c.name, err != nil, c.exceeded, err)
+		}
+	}
+}
+
+func TestAdmitWithoutQuota(t *testing.T) {
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, database("app", "4Gi"))
+	if err := Admit(c, database("new", ""), nil, "other"); err != nil {
+		t.Errorf("namespace without DatabaseQuota: %s", err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+	measured := database("measured", "1Gi")
+	measured.Status.Server = "reporting"
+	measured.Status.Usage = &dbv1beta1.DatabaseUsage{Size: resource.MustParse("512Mi")}
+	deleting := database("deleting", "8Gi")
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	pending := database("pending", "8Gi")
+	pending.Status = dbv1beta1.DatabaseStatus{}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, database("app", "4Gi"), database("excluded", "2Gi"), measured, deleting, pending)
+
+	status, err := Usage(c, "team", "excluded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Databases != 2 || status.Storage.String() != "5Gi" || status.MeasuredStorage.String() != "512Mi" {
+		t.Errorf("Usage = %d databases, %s storage, %s measured", status.Databases, status.Storage.String(), status.MeasuredStorage.String())
+	}
+	if len(status.Servers) != 2 || status.Servers[0] != "default" || status.Servers[1] != "reporting" {
+		t.Errorf("Servers = %v", status.Servers)
+	}
+}
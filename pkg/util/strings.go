@@ -0,0 +1,22 @@
+// Package util holds helpers shared by the controllers and webhooks.
+package util
+
+// Contains reports if s is in list
+func Contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove returns list without s
+func Remove(list []string, s string) []string {
+	for i, v := range list {
+		if v == s {
+			list = append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
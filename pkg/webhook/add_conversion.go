@@ -0,0 +1,10 @@
+package webhook
+
+import (
+	"db-operator/pkg/webhook/conversion"
+)
+
+func init() {
+	// AddToManagerFuncs is a list of functions to register webhook handlers.
+	AddToManagerFuncs = append(AddToManagerFuncs, conversion.Add)
+}
@@ -0,0 +1,159 @@
+package conversion
+
+import (
+	"db-operator/pkg/conversion"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("webhook_conversion")
+
+// Path is where the apiserver sends ConversionReviews for our CRDs
+const Path = "/convert"
+
+// Add registers the conversion webhook handler on mux
+func Add(mgr manager.Manager, mux *http.ServeMux) error {
+	mux.Handle(Path, &Webhook{scheme: mgr.GetScheme()})
+	return nil
+}
+
+// Webhook converts custom resources between their versions using the hub and spoke model.
+// Every kind must have one version implementing conversion.Hub and the other versions
+// implementing conversion.Convertible.
+type Webhook struct {
+	scheme *runtime.Scheme
+}
+
+var _ http.Handler = &Webhook{}
+
+// ServeHTTP handles both apiextensions.k8s.io/v1 and v1beta1 ConversionReviews, they share the same layout
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &apiextensionsv1beta1.ConversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+		log.Error(err, "Unable to decode ConversionReview")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "ConversionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = wh.handle(review.Request)
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Error(err, "Unable to encode ConversionReview")
+	}
+}
+
+func (wh *Webhook) handle(req *apiextensionsv1beta1.ConversionRequest) *apiextensionsv1beta1.ConversionResponse {
+	resp := &apiextensionsv1beta1.ConversionResponse{UID: req.UID}
+
+	desired, err := schema.ParseGroupVersion(req.DesiredAPIVersion)
+	if err != nil {
+		resp.Result = failure(err)
+		return resp
+	}
+
+	for _, raw := range req.Objects {
+		converted, err := wh.convert(raw.Raw, desired)
+		if err != nil {
+			log.Error(err, "Conversion failed", "DesiredAPIVersion", req.DesiredAPIVersion)
+			resp.ConvertedObjects = nil
+			resp.Result = failure(err)
+			return resp
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	resp.Result = metav1.Status{Status: metav1.StatusSuccess}
+
+	return resp
+}
+
+func (wh *Webhook) convert(raw []byte, desired schema.GroupVersion) ([]byte, error) {
+	typeMeta := &metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, typeMeta); err != nil {
+		return nil, err
+	}
+	srcGVK := typeMeta.GroupVersionKind()
+	dstGVK := desired.WithKind(srcGVK.Kind)
+
+	src, err := wh.scheme.New(srcGVK)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, src); err != nil {
+		return nil, err
+	}
+	if srcGVK == dstGVK {
+		return raw, nil
+	}
+
+	dst, err := wh.scheme.New(dstGVK)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isHub(src) && isConvertible(dst):
+		err = dst.(conversion.Convertible).ConvertFrom(src.(conversion.Hub))
+	case isConvertible(src) && isHub(dst):
+		err = src.(conversion.Convertible).ConvertTo(dst.(conversion.Hub))
+	case isConvertible(src) && isConvertible(dst):
+		// spoke to spoke goes through the hub
+		var hub conversion.Hub
+		hub, err = wh.hubFor(srcGVK)
+		if err == nil {
+			err = src.(conversion.Convertible).ConvertTo(hub)
+		}
+		if err == nil {
+			err = dst.(conversion.Convertible).ConvertFrom(hub)
+		}
+	default:
+		err = fmt.Errorf("%s and %s are not convertible", srcGVK, dstGVK)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dst.GetObjectKind().SetGroupVersionKind(dstGVK)
+	return json.Marshal(dst)
+}
+
+// hubFor finds the Hub version of the kind in the scheme
+func (wh *Webhook) hubFor(gvk schema.GroupVersionKind) (conversion.Hub, error) {
+	for _, gv := range wh.scheme.PrioritizedVersionsForGroup(gvk.Group) {
+		obj, err := wh.scheme.New(gv.WithKind(gvk.Kind))
+		if err != nil {
+			continue
+		}
+		if hub, ok := obj.(conversion.Hub); ok {
+			return hub, nil
+		}
+	}
+	return nil, fmt.Errorf("no hub version found for %s", gvk.GroupKind())
+}
+
+func isHub(obj runtime.Object) bool {
+	_, ok := obj.(conversion.Hub)
+	return ok
+}
+
+func isConvertible(obj runtime.Object) bool {
+	_, ok := obj.(conversion.Convertible)
+	return ok
+}
+
+func failure(err error) metav1.Status {
+	return metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+}
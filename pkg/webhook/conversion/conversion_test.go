@@ -0,0 +1,140 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"db-operator/pkg/apis"
+	"db-operator/pkg/apis/db/v1alpha1"
+	"db-operator/pkg/apis/db/v1beta1"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newWebhook(t *testing.T) *Webhook {
+	scheme := runtime.NewScheme()
+	if err := apis.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return &Webhook{scheme: scheme}
+}
+
+// review sends the objects to the webhook and returns its response
+func review(t *testing.T, wh *Webhook, desired string, objects ...interface{}) *apiextensionsv1beta1.ConversionResponse {
+	req := &apiextensionsv1beta1.ConversionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "ConversionReview"},
+		Request:  &apiextensionsv1beta1.ConversionRequest{UID: "42", DesiredAPIVersion: desired},
+	}
+	for _, obj := range objects {
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Request.Objects = append(req.Request.Objects, runtime.RawExtension{Raw: raw})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	wh.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+	resp := &apiextensionsv1beta1.ConversionReview{}
+	if err := json.NewDecoder(rec.Body).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Response == nil || resp.Response.UID != "42" {
+		t.Fatalf("response doesn't answer the request: %+v", resp.Response)
+	}
+	if resp.APIVersion != "apiextensions.k8s.io/v1" {
+		t.Errorf("response has apiVersion %s, the request apiextensions.k8s.io/v1", resp.APIVersion)
+	}
+	return resp.Response
+}
+
+func TestConvertBothWays(t *testing.T) {
+	wh := newWebhook(t)
+	alpha := &v1alpha1.Database{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "db.clarizen.cloud/v1alpha1", Kind: "Database"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"},
+		Spec:       v1alpha1.DatabaseSpec{Type: "postgres", Users: []string{"reader"}, Drop: true},
+	}
+
+	resp := review(t, wh, "db.clarizen.cloud/v1beta1", alpha)
+	if resp.Result.Status != metav1.StatusSuccess || len(resp.ConvertedObjects) != 1 {
+		t.Fatalf("conversion to v1beta1 failed: %+v", resp.Result)
+	}
+	beta := &v1beta1.Database{}
+	if err := json.Unmarshal(resp.ConvertedObjects[0].Raw, beta); err != nil {
+		t.Fatal(err)
+	}
+	if beta.APIVersion != "db.clarizen.cloud/v1beta1" || beta.Kind != "Database" {
+		t.Errorf("converted object is a %s %s", beta.APIVersion, beta.Kind)
+	}
+	if beta.Spec.DeletionPolicy != v1beta1.DeletionPolicyDelete || len(beta.Spec.Users) != 1 || beta.Spec.Users[0].Name != "reader" {
+		t.Errorf("spec wasn't converted: %+v", beta.Spec)
+	}
+
+	beta.Spec.DeletionPolicy = v1beta1.DeletionPolicySoftDelete
+	resp = review(t, wh, "db.clarizen.cloud/v1alpha1", beta)
+	if resp.Result.Status != metav1.StatusSuccess || len(resp.ConvertedObjects) != 1 {
+		t.Fatalf("conversion to v1alpha1 failed: %+v", resp.Result)
+	}
+	back := &v1alpha1.Database{}
+	if err := json.Unmarshal(resp.ConvertedObjects[0].Raw, back); err != nil {
+		t.Fatal(err)
+	}
+	if back.APIVersion != "db.clarizen.cloud/v1alpha1" || !back.Spec.Drop {
+		t.Errorf("spec wasn't converted: %s %+v", back.APIVersion, back.Spec)
+	}
+	if !strings.Contains(back.Annotations["db.clarizen.cloud/v1beta1-spec"], "SoftDelete") {
+		t.Errorf("SoftDelete isn't preserved: %v", back.Annotations)
+	}
+}
+
+func TestConvertSameVersion(t *testing.T) {
+	beta := &v1beta1.Database{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "db.clarizen.cloud/v1beta1", Kind: "Database"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+	}
+	resp := review(t, newWebhook(t), "db.clarizen.cloud/v1beta1", beta)
+	if resp.Result.Status != metav1.StatusSuccess || len(resp.ConvertedObjects) != 1 {
+		t.Fatalf("conversion failed: %+v", resp.Result)
+	}
+}
+
+func TestConvertFailures(t *testing.T) {
+	wh := newWebhook(t)
+	alpha := &v1alpha1.Database{TypeMeta: metav1.TypeMeta{APIVersion: "db.clarizen.cloud/v1alpha1", Kind: "Database"}}
+	unknown := &v1alpha1.Database{TypeMeta: metav1.TypeMeta{APIVersion: "db.clarizen.cloud/v1alpha1", Kind: "Table"}}
+
+	for name, resp := range map[string]*apiextensionsv1beta1.ConversionResponse{
+		"unknown version": review(t, wh, "db.clarizen.cloud/v9", alpha),
+		"unknown kind":    review(t, wh, "db.clarizen.cloud/v1beta1", alpha, unknown),
+		"invalid version": review(t, wh, "a/b/c", alpha),
+	} {
+		if resp.Result.Status != metav1.StatusFailure || resp.Result.Message == "" {
+			t.Errorf("%s: expected a failure, got %+v", name, resp.Result)
+		}
+		if len(resp.ConvertedObjects) != 0 {
+			t.Errorf("%s: a failed review must not return objects", name)
+		}
+	}
+
+	for _, body := range []string{"{", `{"kind":"ConversionReview"}`} {
+		rec := httptest.NewRecorder()
+		wh.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", body, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
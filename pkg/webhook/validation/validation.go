@@ -0,0 +1,156 @@
+package validation
+
+import (
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+	"db-operator/pkg/conversion"
+	"db-operator/pkg/quota"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("webhook_validation")
+
+// Path is where the apiserver sends AdmissionReviews for Databases
+const Path = "/validate-database"
+
+// Add registers the validating webhook handler on mux
+func Add(mgr manager.Manager, mux *http.ServeMux) error {
+	mux.Handle(Path, &Webhook{
+		client:  mgr.GetClient(),
+		decoder: serializer.NewCodecFactory(mgr.GetScheme()).UniversalDeserializer(),
+	})
+	return nil
+}
+
+// Webhook validates Databases on create and update. It refuses Databases which don't fit
+// into the DatabaseQuotas of their namespace, the Database controller checks the same
+// before provisioning in case the webhook isn't installed.
+type Webhook struct {
+	client  client.Client
+	decoder runtime.Decoder
+}
+
+var _ http.Handler = &Webhook{}
+
+// ServeHTTP handles admission.k8s.io/v1 and v1beta1 AdmissionReviews, they share the same layout
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &admissionv1beta1.AdmissionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+		log.Error(err, "Unable to decode AdmissionReview")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "AdmissionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = wh.handle(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Error(err, "Unable to encode AdmissionReview")
+	}
+}
+
+func (wh *Webhook) handle(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	db, err := wh.decode(req.Object.Raw)
+	if err != nil {
+		return denied(err)
+	}
+
+	var old *dbv1beta1.Database
+	if req.Operation == admissionv1beta1.Update {
+		if old, err = wh.decode(req.OldObject.Raw); err != nil {
+			return denied(err)
+		}
+	}
+	// Requests for the namespace, e.g. on create, may not carry it in the object
+	if db.Namespace == "" {
+		db.Namespace = req.Namespace
+	}
+
+	// The finalizer and annotations have to be editable whatever the spec, or Databases which
+	// predate a DatabaseQuota or a stricter check could never be deleted
+	if old != nil && (db.GetDeletionTimestamp() != nil || equality.Semantic.DeepEqual(old.Spec, db.Spec)) {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}
+	}
+
+	if old != nil && old.Status.Phase != "" {
+		if changed := db.Spec.Settings.ImmutableChanges(old.Spec.Settings); len(changed) > 0 {
+			return denied(fmt.Errorf("spec.settings.%s can't be changed after the database is created",
+				strings.Join(changed, ", spec.settings.")))
+		}
+	}
+
+	if refusal := db.OwnerRefusal(); refusal != "" {
+		return denied(fmt.Errorf("spec.settings.%s", refusal))
+	}
+
+	if refusal := db.RoleSettingsRefusal(); refusal != "" {
+		return denied(fmt.Errorf("spec.users: %s", refusal))
+	}
+
+	// The allowed servers are only checked when the Database asks for another server
+	server := serverName(db)
+	if old != nil && server == serverName(old) {
+		server = ""
+	}
+	if err := quota.Admit(wh.client, db, old, server); err != nil {
+		return denied(err)
+	}
+
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+// decode reads a Database of any served version into the hub version
+func (wh *Webhook) decode(raw []byte) (*dbv1beta1.Database, error) {
+	obj, _, err := wh.decoder.Decode(raw, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode Database: %s", err)
+	}
+
+	switch o := obj.(type) {
+	case *dbv1beta1.Database:
+		return o, nil
+	case conversion.Convertible:
+		db := &dbv1beta1.Database{}
+		if err := o.ConvertTo(db); err != nil {
+			return nil, err
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unexpected object %T", obj)
+	}
+}
+
+// serverName is the server the Database asks for or is placed on.
+// The default server isn't known here, the Database controller checks it.
+func serverName(db *dbv1beta1.Database) string {
+	switch {
+	case db.Spec.ServerRef != nil:
+		return db.Spec.ServerRef.Name
+	default:
+		return db.Status.Server
+	}
+}
+
+func denied(err error) *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result:  &metav1.Status{Status: metav1.StatusFailure, Message: err.Error(), Reason: metav1.StatusReasonForbidden},
+	}
+}
@@ -0,0 +1,161 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"db-operator/pkg/apis"
+	dbv1beta1 "db-operator/pkg/apis/db/v1beta1"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newWebhook(t *testing.T, objs ...runtime.Object) *Webhook {
+	// The fake client decodes lists with the client-go scheme
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+	return &Webhook{
+		client:  fake.NewFakeClientWithScheme(scheme.Scheme, objs...),
+		decoder: serializer.NewCodecFactory(scheme.Scheme).UniversalDeserializer(),
+	}
+}
+
+// restrictiveQuota only allows a server none of the Databases are on
+func restrictiveQuota() *dbv1beta1.DatabaseQuota {
+	return &dbv1beta1.DatabaseQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "servers", Namespace: "team"},
+		Spec:       dbv1beta1.DatabaseQuotaSpec{AllowedServers: []string{"reporting"}},
+	}
+}
+
+// created is a provisioned Database which doesn't pass today's checks: its server isn't allowed
+// by the quota and it's owned by a role which isn't allowed anymore
+func created() *dbv1beta1.Database {
+	limit := int32(5)
+	return &dbv1beta1.Database{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "db.clarizen.cloud/v1beta1", Kind: "Database"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"},
+		Spec: dbv1beta1.DatabaseSpec{
+			Type:     "postgres",
+			Settings: &dbv1beta1.DatabaseSettings{Owner: "legacy"},
+			Users:    []dbv1beta1.DatabaseUser{{Name: "reader", RoleSettings: dbv1beta1.RoleSettings{ConnectionLimit: &limit}}},
+		},
+		Status: dbv1beta1.DatabaseStatus{Phase: "Created", Server: "default"},
+	}
+}
+
+func request(t *testing.T, op admissionv1beta1.Operation, db, old *dbv1beta1.Database) *admissionv1beta1.AdmissionRequest {
+	req := &admissionv1beta1.AdmissionRequest{Operation: op, Namespace: "team"}
+	var err error
+	if req.Object.Raw, err = json.Marshal(db); err != nil {
+		t.Fatal(err)
+	}
+	if old != nil {
+		if req.OldObject.Raw, err = json.Marshal(old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return req
+}
+
+func TestUpdatesWithoutSpecChanges(t *testing.T) {
+	wh := newWebhook(t, restrictiveQuota())
+	now := metav1.Now()
+
+	for name, edit := range map[string]func(db *dbv1beta1.Database){
+		"finalizer added":   func(db *dbv1beta1.Database) { db.Finalizers = []string{"finalizer.db.clarizen.cloud"} },
+		"finalizer removed": func(db *dbv1beta1.Database) { db.DeletionTimestamp = &now; db.Finalizers = nil },
+		"annotation added": func(db *dbv1beta1.Database) {
+			db.Annotations = map[string]string{"db.clarizen.cloud/confirm-drop": "app"}
+		},
+		"spec changed while deleting": func(db *dbv1beta1.Database) {
+			db.DeletionTimestamp = &now
+			db.Spec.Parameters = map[string]string{"work_mem": "64MB"}
+		},
+	} {
+		old := created()
+		if name == "finalizer removed" {
+			old.DeletionTimestamp = &now
+			old.Finalizers = []string{"finalizer.db.clarizen.cloud"}
+		}
+		db := old.DeepCopy()
+		edit(db)
+		if resp := wh.handle(request(t, admissionv1beta1.Update, db, old)); !resp.Allowed {
+			t.Errorf("%s: denied: %s", name, resp.Result.Message)
+		}
+	}
+
+	// Only the quota is against the Database pinned to a server it excludes
+	old := created()
+	old.Spec.Settings, old.Spec.Users = nil, nil
+	old.Spec.ServerRef = &dbv1beta1.ServerReference{Name: "default"}
+	db := old.DeepCopy()
+	db.Finalizers = []string{"finalizer.db.clarizen.cloud"}
+	if resp := wh.handle(request(t, admissionv1beta1.Update, db, old)); !resp.Allowed {
+		t.Errorf("finalizer added on an excluded server: denied: %s", resp.Result.Message)
+	}
+}
+
+func TestUpdatesWithSpecChanges(t *testing.T) {
+	wh := newWebhook(t, restrictiveQuota())
+
+	for _, c := range []struct {
+		name    string
+		edit    func(db *dbv1beta1.Database)
+		allowed bool
+	}{
+		{"other spec changes keep the server", func(db *dbv1beta1.Database) {
+			db.Spec.Settings = nil
+			db.Spec.Users = nil
+			db.Spec.Parameters = map[string]string{"work_mem": "64MB"}
+		}, true},
+		{"move to an allowed server", func(db *dbv1beta1.Database) {
+			db.Spec.Settings, db.Spec.Users = nil, nil
+			db.Spec.ServerRef = &dbv1beta1.ServerReference{Name: "reporting"}
+		}, true},
+		{"move to a server the quota doesn't allow", func(db *dbv1beta1.Database) {
+			db.Spec.Settings, db.Spec.Users = nil, nil
+			db.Spec.ServerRef = &dbv1beta1.ServerReference{Name: "other"}
+		}, false},
+		{"owner of another role", func(db *dbv1beta1.Database) {
+			db.Spec.Users = nil
+			db.Spec.Parameters = map[string]string{"work_mem": "64MB"}
+		}, false},
+		{"connection limit of a shared role", func(db *dbv1beta1.Database) {
+			db.Spec.Settings = nil
+		}, false},
+		{"immutable setting", func(db *dbv1beta1.Database) {
+			db.Spec.Users = nil
+			db.Spec.Settings = &dbv1beta1.DatabaseSettings{Encoding: "LATIN1"}
+		}, false},
+	} {
+		old := created()
+		db := old.DeepCopy()
+		c.edit(db)
+		if resp := wh.handle(request(t, admissionv1beta1.Update, db, old)); resp.Allowed != c.allowed {
+			t.Errorf("%s: allowed is %t, want %t: %+v", c.name, resp.Allowed, c.allowed, resp.Result)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	wh := newWebhook(t, restrictiveQuota())
+
+	db := created()
+	db.Status = dbv1beta1.DatabaseStatus{}
+	db.Spec.Settings, db.Spec.Users = nil, nil
+	db.Spec.ServerRef = &dbv1beta1.ServerReference{Name: "default"}
+	if resp := wh.handle(request(t, admissionv1beta1.Create, db, nil)); resp.Allowed {
+		t.Error("create on a server the quota doesn't allow was admitted")
+	}
+	db.Spec.ServerRef.Name = "reporting"
+	if resp := wh.handle(request(t, admissionv1beta1.Create, db, nil)); !resp.Allowed {
+		t.Errorf("create on an allowed server was denied: %s", resp.Result.Message)
+	}
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("webhook")
+
+// AddToManagerFuncs is a list of functions to register all webhook handlers on the Server
+var AddToManagerFuncs []func(manager.Manager, *http.ServeMux) error
+
+// Server serves the operator webhooks over TLS.
+// The serving certificate is read from tls.crt and tls.key in CertDir.
+type Server struct {
+	Host    string
+	Port    int32
+	CertDir string
+
+	mux *http.ServeMux
+}
+
+// AddToManager registers all webhooks on s and adds s to the Manager.
+// Webhooks are skipped with a log message if there is no serving certificate.
+func AddToManager(m manager.Manager, s *Server) error {
+	if _, err := os.Stat(filepath.Join(s.CertDir, "tls.crt")); err != nil {
+		log.Info("No webhook serving certificate found, webhooks are disabled", "CertDir", s.CertDir)
+		return nil
+	}
+
+	s.mux = http.NewServeMux()
+	for _, f := range AddToManagerFuncs {
+		if err := f(m, s.mux); err != nil {
+			return err
+		}
+	}
+	return m.Add(s)
+}
+
+// Start implements manager.Runnable
+func (s *Server) Start(stop <-chan struct{}) error {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:      fmt.Sprintf("%s:%d", s.Host, s.Port),
+		Handler:   s.mux,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("Serving webhooks", "Addr", srv.Addr)
+		errCh <- srv.ListenAndServeTLS("", "")
+	}()
+
+	select {
+	case <-stop:
+		return srv.Shutdown(context.Background())
+	case err := <-errCh:
+		return err
+	}
+}